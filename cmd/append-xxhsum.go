@@ -1,18 +1,18 @@
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
-	"time"
+	"runtime"
+	"sort"
+	"strings"
 
-	"github.com/briandowns/spinner"
-	"github.com/cespare/xxhash/v2"
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/manifest"
 	"github.com/lukasz-lobocki/append-xxhsum/pkg/utils"
 )
 
@@ -22,142 +22,268 @@ var (
 	version string = "development"
 )
 
-// `searchDir` walks the `root` directory and adds hashes, missing in the `dict`, to the `xxhsumFilepath` file.
-func searchDir(root string, dict map[string]string, xxhsumFilepath string, bsdStyle bool, verbose bool) int {
+// `options` holds the settings of a run, shared by the walker, the hashing workers and the writer.
+type options struct {
+	xxhsumFilepath string             // `xxhsumFilepath` is the file to append to. Entries are relative to its directory.
+	algorithm      manifest.Algorithm // `algorithm` hashes new files.
+	bsdStyle       bool               // `bsdStyle` selects BSD-style lines over GNU-style ones.
+	verbose        bool               // `verbose` increases the verbosity.
+	jobs           int                // `jobs` is the number of files hashed in parallel.
+	keepOrder      bool               // `keepOrder` appends lines in walk order.
+	update         bool               // `update` re-hashes listed files that changed since they were hashed.
+	rehash         bool               // `rehash` makes `update` re-hash every listed file, regardless of size and mtime.
+	dryRun         bool               // `dryRun` reports changes without writing anything.
+	excludes       []string           // `excludes` are gitignore-style patterns of paths not to be hashed.
+	includes       []string           // `includes` are gitignore-style patterns of the only paths to be hashed.
+	skipReport     bool               // `skipReport` reports the number of non-regular files skipped, by their type.
+	zero           bool               // `zero` terminates lines of the xxhsum file with NUL instead of newline, leaving file names unescaped.
+	keepIndex      bool               // `keepIndex` keeps the index up to date on appending runs too, not only with `update`.
+	archives       bool               // `archives` treats tar and zip archives as directories of their members.
+	prescan        bool               // `prescan` totals files and bytes to be hashed before hashing them, for progress and ETA.
+}
+
+// Outputs the style of new lines.
+func (opts options) style() manifest.Style {
+	if opts.bsdStyle {
+		return manifest.BSD
+	}
+	return manifest.GNU
+}
+
+// `searchResult` sums up the changes found by `searchDir`.
+type searchResult struct {
+	appended int                // `appended` counts the lines appended for new files.
+	modified int                // `modified` counts listed files whose size, mtime or inode differ from the index, without re-hashing them.
+	changed  *manifest.Manifest // `changed` holds the new entries of changed files.
+	skipped  map[string]int     // `skipped` counts non-regular files skipped, by their type.
+}
+
+// `searchDir` walks the `roots` trees and the listed `files`, and adds hashes, missing in the `dict`, to the xxhsum file
+// through the `appender`, see `manifest.Search`. With `opts.update`, listed files are re-hashed if their size, mtime or
+// inode differ from those in the `index`, which is updated with every file hashed, unless it is nil.
+// Files and bytes hashed are counted in `p`, unless it is nil; failed and abandoned ones are not. With `opts.prescan`, the same walk totals them first, without hashing.
+// Once `ctx` is done, walking stops and hashing is abandoned. Lines of files hashed by then are still appended.
+func searchDir(ctx context.Context, roots []manifest.Root, files []string, dict *manifest.Manifest, index map[string]manifest.FileMeta, appender *manifest.Appender, p *progress, opts options) searchResult {
 
 	var (
-		line string // `line` contains string to be appended to the `xxhsumFilepath` file.
-		i    int    // `int` counts the number of additions.
+		found searchResult           = searchResult{changed: manifest.New(), skipped: make(map[string]int)} // `found` collects the changes.
+		sopts manifest.SearchOptions = manifest.SearchOptions{                                              // `sopts` are the settings of the search.
+			WalkOptions: manifest.WalkOptions{
+				Manifest: opts.xxhsumFilepath,
+				Excludes: opts.excludes,
+				Includes: opts.includes,
+				Archives: opts.archives,
+			},
+			HashOptions: manifest.HashOptions{Jobs: opts.jobs, KeepOrder: opts.keepOrder, Reader: p.reader},
+			Algorithm:   opts.algorithm,
+			Update:      opts.update,
+			Rehash:      opts.rehash,
+			DryRun:      opts.dryRun,
+		}
 	)
 
-	err := filepath.WalkDir(root, func(path string, di fs.DirEntry, err error) error {
-		if err != nil {
-			log.Printf("error accessing path %s; skipping %v\n", path, err)
-			return nil
+	if opts.prescan {
+		// Total the files to be hashed, as the search below finds them.
+		p.beginScan()
+		manifest.Prescan(ctx, roots, files, dict, index, sopts, p.addTotal)
+		p.endScan()
+	}
+
+	sopts.Skipped = func(skip manifest.Skip) {
+		if skip.Reason == manifest.SkipNonRegular {
+			found.skipped[skip.Type]++
 		}
+		if opts.verbose {
+			log.Printf(utils.GREEN+"INFO"+utils.RESET+" %s %s; skipping\n", skip.Path, skipReason(skip))
+		}
+	}
+	sopts.Errors = func(err error) {
+		log.Printf("%v; skipping\n", err)
+	}
+
+	manifest.Search(ctx, roots, files, dict, index, sopts, func(f manifest.Found) {
+		recordFound(&found, f, appender, p, opts)
+	})
+	return found
+}
+
+// Outputs why the path of `skip` is skipped, for messages.
+func skipReason(skip manifest.Skip) string {
+	switch skip.Reason {
+	case manifest.SkipExcluded:
+		return "excluded"
+	case manifest.SkipNotIncluded:
+		return "not included"
+	case manifest.SkipNonRegular:
+		return "is a " + skip.Type
+	case manifest.SkipDirectory:
+		return "is a directory"
+	default:
+		return "is the xxhsum file or its companion"
+	}
+}
+
+// `recordFound` records the file `f` in `found`: the line of a new file is appended through the `appender`,
+// and the new entry of a re-hashed file that changed is collected. Files hashed are counted in `p`.
+func recordFound(found *searchResult, f manifest.Found, appender *manifest.Appender, p *progress, opts options) {
 
-		// Skip directories and symbolic links.
-		shouldReturn, returnValue := skipDirs(di)
-		if shouldReturn {
-			return returnValue
+	if f.Hashed() {
+		p.done(f.Read, f.Err)
+	}
+
+	switch f.Finding {
+	case manifest.FoundNew:
+		// Emit the line of the new file.
+		found.appended = found.appended + emitLine(appender, f.Entry, opts)
+	case manifest.FoundChanged:
+		fmt.Printf("%s: CHANGED\n", f.Path)
+		found.changed.Add(f.Entry)
+	case manifest.FoundUnchanged:
+		if opts.verbose {
+			log.Printf(utils.GREEN+"INFO"+utils.RESET+" %s unchanged\n", f.Path)
+		}
+	case manifest.FoundUnreadable:
+		if !errors.Is(f.Err, context.Canceled) {
+			// Not abandoned on interrupt.
+			log.Printf("error calculating xxHash: %v\n", f.Err)
 		}
+	case manifest.FoundModified:
+		// Size, mtime or inode differ from the recorded ones, but re-hashing is up to `opts.update`.
+		fmt.Printf("%s: MODIFIED\n", f.Path)
+		found.modified++
+	case manifest.FoundUnrecognised:
+		log.Printf("error in entry %s; skipping unrecognised hash: %s\n", f.Path, f.Previous.Hash)
+	case manifest.FoundUnhashed:
+		if opts.verbose {
+			log.Printf(utils.GREEN+"INFO"+utils.RESET+" %s is new; skipping in dry run\n", f.Path)
+		}
+	case manifest.FoundListed:
+		if opts.verbose {
+			log.Printf(utils.GREEN+"INFO"+utils.RESET+" %s exists; skipping\n", f.Path)
+		}
+	}
+}
 
-		if rel_path, err := filepath.Rel(filepath.Dir(xxhsumFilepath), path); err != nil {
-			log.Printf("error resolving relative path; skipping %v\n", err)
-		} else {
-			if _, ok := dict[rel_path]; ok {
-				// `rel_path` key already found in `dict`. Do nothing.
-				if verbose {
-					log.Printf(utils.GREEN+"INFO"+utils.RESET+" %s exists; skipping\n", rel_path)
-				}
-			} else {
-				// `rel_path` key missing from `dict`. Append. an entry.
-				if checksum, err := calculateXXHash(path); err != nil {
-					log.Printf("error calculating xxHash: %v\n", err)
-				} else {
-					// Calculate the line to be appended.
-					line = calculateLine(bsdStyle, rel_path, checksum)
-
-					// Emit the calculated line.
-					i = i + emitLine(xxhsumFilepath, line, verbose)
-				}
+// `checkDict` re-hashes every file listed in the `dict` and reports OK, FAILED or MISSING for each entry.
+// Each entry is re-hashed with the algorithm its hash was recorded with.
+// With `opts.archives`, members of archives are read back through their archive, which is read once for all of them.
+// Once `ctx` is done, checking stops. Entries checked by then are counted.
+func checkDict(ctx context.Context, dict *manifest.Manifest, opts options) (ok int, failed int, missing int) {
+
+	manifest.Verify(ctx, dict, filepath.Dir(opts.xxhsumFilepath), opts.archives, func(result manifest.VerifyResult) {
+		switch result.Status {
+		case manifest.OK:
+			if opts.verbose {
+				fmt.Printf("%s: OK\n", result.Entry.Path)
 			}
+			ok++
+		case manifest.Failed:
+			fmt.Printf("%s: FAILED\n", result.Entry.Path)
+			failed++
+		case manifest.Missing:
+			fmt.Printf("%s: MISSING\n", result.Entry.Path)
+			missing++
+		case manifest.Unreadable:
+			log.Printf("error calculating xxHash: %v\n", result.Err)
+			fmt.Printf("%s: FAILED open or read\n", result.Entry.Path)
+			failed++
+		case manifest.Unrecognised:
+			log.Printf("error in entry %s: unrecognised hash: %s\n", result.Entry.Path, result.Entry.Hash)
+			fmt.Printf("%s: FAILED\n", result.Entry.Path)
+			failed++
 		}
-		return nil
 	})
+	return
+}
 
-	if err != nil {
-		log.Fatalf("Error walking the path %s: %v\n", root, err)
+// `checkStyle` outputs an error if lines of the requested style would be appended to a file of the other `style`.
+func checkStyle(style manifest.Style, bsdStyle bool, xxhsumFilepath string) error {
+	switch true {
+	case style == manifest.GNU && bsdStyle:
+		return fmt.Errorf("%s has GNU-style lines; omit --bsd-style to append to it", xxhsumFilepath)
+	case style == manifest.BSD && !bsdStyle:
+		return fmt.Errorf("%s has BSD-style lines; use --bsd-style to append to it", xxhsumFilepath)
+	case style == manifest.Mixed:
+		log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %s mixes GNU-style and BSD-style lines\n", xxhsumFilepath)
 	}
-
-	return i
+	return nil
 }
 
-// Formats output string according to BSD or default specifiaction.
-func calculateLine(bsdStyle bool, relPath string, checksum string) string {
-	if bsdStyle {
-		return fmt.Sprintf("XXH64 (%s) = %s\n", relPath, checksum)
+// `checkTerminator` outputs an error if lines of the file are `mismatched`, terminated otherwise than `zero` tells.
+func checkTerminator(mismatched bool, zero bool, xxhsumFilepath string) error {
+	switch true {
+	case mismatched && zero:
+		return fmt.Errorf("%s has newline-terminated lines; omit --zero to use it", xxhsumFilepath)
+	case mismatched:
+		return fmt.Errorf("%s has NUL-terminated lines; use --zero to use it", xxhsumFilepath)
 	}
-	return fmt.Sprintf("%s *%s\n", checksum, relPath)
+	return nil
 }
 
-// Outputs a line.
-func emitLine(xxhsumFilepath string, line string, verbose bool) (linesEmitted int) {
+// Outputs the line of `entry`.
+func emitLine(appender *manifest.Appender, entry manifest.Entry, opts options) (linesEmitted int) {
 	// Emit to conslole.
-	if verbose {
-		fmt.Print(line)
+	if opts.verbose {
+		fmt.Print(entry.Line(opts.style(), opts.zero))
 	}
-	// Emit to file, appending the `line`.
-	if err := appendToFile(xxhsumFilepath, line); err != nil {
-		log.Printf("error appending to file %s; skipping %v\n", xxhsumFilepath, err)
+	// Emit to file, appending the `entry`.
+	if err := appender.Append(entry); err != nil {
+		log.Printf("%v; skipping\n", err)
 	} else {
 		linesEmitted = 1
 	}
 	return
 }
 
-// Outputs if parameter is directory or symbolic-link.
-func skipDirs(di fs.DirEntry) (bool, error) {
-	if di.IsDir() {
-		return true, nil
+// `reportSkipped` logs the number of `skipped` files of each type.
+func reportSkipped(skipped map[string]int) {
+	fileTypes := make([]string, 0, len(skipped))
+	for fileType := range skipped {
+		fileTypes = append(fileTypes, fileType)
 	}
+	sort.Strings(fileTypes)
 
-	if fileInfo, err := di.Info(); err != nil {
-		log.Printf("error accessing file %s; skipping %v\n", di.Name(), err)
-		return true, nil
-	} else {
-		if fileInfo.Mode()&os.ModeSymlink != 0 {
-			return true, nil
-		}
+	for _, fileType := range fileTypes {
+		log.Printf("%d %s(s) skipped\n", skipped[fileType], fileType)
 	}
-	return false, nil
 }
 
-// Outputs XXH64 hash for the file.
-func calculateXXHash(filePath string) (string, error) {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
+// `acquireLock` locks the lock file of `xxhsumFilepath`, `shared` by runs only reading it.
+// Runs only reading it go on without the lock if the lock file can't be created, e.g. on read-only media.
+// Waiting for the lock stops once `ctx` is done, outputting its error.
+func acquireLock(ctx context.Context, xxhsumFilepath string, shared bool, wait bool) (*utils.Lock, error) {
 
-	hash := xxhash.New()
+	var (
+		lockedErr *utils.LockedError = nil
+	)
 
-	if _, err := io.Copy(hash, file); err != nil {
-		return "", err
+	lock, err := utils.AcquireLockContext(ctx, utils.LockFilepath(xxhsumFilepath), shared, wait)
+	switch true {
+	case ctx.Err() != nil:
+		return nil, err
+	case errors.As(err, &lockedErr):
+		return nil, fmt.Errorf("%w; another run is using %s, use --wait to wait for it", err, xxhsumFilepath)
+	case err != nil && shared:
+		log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %v; going on without lock\n", err)
+		return nil, nil
 	}
-
-	return fmt.Sprintf("%0*x", 16, hash.Sum64()), nil
+	return lock, err
 }
 
-// Appends a string to the file.
-func appendToFile(filename string, content string) error {
-	// Open the file in append mode, create it if it doesn't exist
-	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
-	if err != nil {
-		return err
-	}
-
-	// Create a buffered writer for efficient writing
-	writer := bufio.NewWriter(file)
-
-	// Write the content to the file
-	_, err = writer.WriteString(content)
-	if err != nil {
-		return err
+// Outputs the searched `givenPaths`, and the list of files `filesFrom` if given, for messages.
+func describeSources(givenPaths []string, filesFrom string) string {
+	sources := append([]string{}, givenPaths...)
+	if filesFrom == utils.Stdin {
+		sources = append(sources, "files listed in stdin")
+	} else if filesFrom != "" {
+		sources = append(sources, "files listed in "+filesFrom)
 	}
-
-	// Flush the buffer to ensure the content is written
-	err = writer.Flush()
-	if err != nil {
-		return err
-	}
-	return file.Close()
+	return strings.Join(sources, ", ")
 }
 
 // Prints some DEBUG info.
-func debugVariables(verbose bool, givenPath string, xxhsumFilepath string, xxhsumFileExists bool) {
-	log.Printf(utils.YELLOW+"DEBUG"+utils.RESET+" given_path=%v\n", givenPath)
+func debugVariables(verbose bool, givenPaths []string, xxhsumFilepath string, xxhsumFileExists bool) {
+	log.Printf(utils.YELLOW+"DEBUG"+utils.RESET+" given_paths=%v\n", givenPaths)
 	log.Printf(utils.YELLOW+"DEBUG"+utils.RESET+" xxhsum-path=%v\n", xxhsumFilepath)
 	log.Printf(utils.YELLOW+"DEBUG"+utils.RESET+" xxhsum-path exists=%t\n", xxhsumFileExists)
 }
@@ -170,18 +296,40 @@ func init() {
 
 // Main routine.
 func main() {
+	os.Exit(run())
+}
+
+// `run` does the work of `main`, and outputs the exit status. Exiting is left to `main`, so that the lock is released
+// and signals handled by default again first, by deferred calls. Errors once the lock is held are returned as status 1
+// rather than fatal for the same reason.
+func run() int {
 
 	var (
-		verbose          bool              = false
-		debug            bool              = false
-		bsdStyle         bool              = false
-		xxhsumFileExists bool              = false
-		xxhsumFilepath   string            = ""
-		givenPath        string            = ""
-		dict             map[string]string = nil
-		err              error             = nil
-		s                *spinner.Spinner  = nil
-		i                int               = 0
+		opts             options                      = options{}
+		debug            bool                         = false
+		check            bool                         = false
+		prune            bool                         = false
+		repair           bool                         = false
+		strict           bool                         = false
+		algorithm        string                       = ""
+		xxhsumFileExists bool                         = false
+		givenPaths       []string                     = []string{}
+		filesFrom        string                       = ""
+		null             bool                         = false
+		files            []string                     = nil
+		dict             *manifest.Manifest           = nil
+		index            map[string]manifest.FileMeta = nil
+		found            searchResult                 = searchResult{}
+		excludes         utils.StringList             = utils.StringList{}
+		includes         utils.StringList             = utils.StringList{}
+		report           manifest.Report              = manifest.Report{}
+		err              error                        = nil
+		appender         *manifest.Appender           = nil
+		wait             bool                         = false
+		lock             *utils.Lock                  = nil
+		ctx              context.Context              = nil
+		stop             context.CancelFunc           = nil
+		p                *progress                    = nil
 	)
 
 	defer func() { dict = nil }()
@@ -189,39 +337,122 @@ func main() {
 	/*
 		Parsing input
 	*/
-	flag.BoolVar(&verbose, "verbose", false, "increase the verbosity.")
-	flag.BoolVar(&verbose, "v", false, "increase the verbosity.")
+	flag.BoolVar(&opts.verbose, "verbose", false, "increase the verbosity.")
+	flag.BoolVar(&opts.verbose, "v", false, "increase the verbosity.")
 	flag.BoolVar(&debug, "debug", false, "show debug information.")
 	flag.BoolVar(&debug, "d", false, "show debug information.")
-	flag.BoolVar(&bsdStyle, "bsd-style", false, "BSD-style checksum lines.")
-	flag.BoolVar(&bsdStyle, "b", false, "BSD-style checksum lines.")
-	flag.StringVar(&xxhsumFilepath, "xxhsum-filepath", "", "FILEPATH to file to append to.")
-	flag.StringVar(&xxhsumFilepath, "x", "", "FILEPATH to file to append to.")
+	flag.BoolVar(&opts.bsdStyle, "bsd-style", false, "BSD-style checksum lines.")
+	flag.BoolVar(&opts.bsdStyle, "b", false, "BSD-style checksum lines.")
+	flag.StringVar(&algorithm, "algorithm", manifest.XXH64.Name, "hash ALGORITHM of new lines.")
+	flag.StringVar(&algorithm, "a", manifest.XXH64.Name, "hash ALGORITHM of new lines.")
+	flag.BoolVar(&check, "check", false, "verify hashes listed in xxhsum file.")
+	flag.BoolVar(&check, "c", false, "verify hashes listed in xxhsum file.")
+	flag.BoolVar(&prune, "prune", false, "remove lines of files that no longer exist.")
+	flag.BoolVar(&prune, "p", false, "remove lines of files that no longer exist.")
+	flag.BoolVar(&repair, "repair", false, "remove malformed lines.")
+	flag.BoolVar(&repair, "R", false, "remove malformed lines.")
+	flag.BoolVar(&strict, "strict", false, "fail on malformed, conflicting or duplicate lines.")
+	flag.BoolVar(&strict, "S", false, "fail on malformed, conflicting or duplicate lines.")
+	flag.BoolVar(&opts.update, "update", false, "re-hash changed files and replace their lines.")
+	flag.BoolVar(&opts.update, "u", false, "re-hash changed files and replace their lines.")
+	flag.BoolVar(&opts.rehash, "rehash", false, "with --update, re-hash every listed file.")
+	flag.BoolVar(&opts.rehash, "r", false, "with --update, re-hash every listed file.")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "report changes without rewriting xxhsum file.")
+	flag.BoolVar(&opts.dryRun, "n", false, "report changes without rewriting xxhsum file.")
+	flag.Var(&excludes, "exclude", "skip paths matching PATTERN. Repeatable.")
+	flag.Var(&excludes, "e", "skip paths matching PATTERN. Repeatable.")
+	flag.Var(&includes, "include", "hash only paths matching PATTERN. Repeatable.")
+	flag.Var(&includes, "i", "hash only paths matching PATTERN. Repeatable.")
+	flag.IntVar(&opts.jobs, "jobs", runtime.NumCPU(), "number of files hashed in parallel.")
+	flag.IntVar(&opts.jobs, "j", runtime.NumCPU(), "number of files hashed in parallel.")
+	flag.BoolVar(&opts.keepOrder, "keep-order", false, "append lines in walk order.")
+	flag.BoolVar(&opts.keepOrder, "k", false, "append lines in walk order.")
+	flag.BoolVar(&opts.skipReport, "skip-report", false, "report non-regular files skipped, by type.")
+	flag.BoolVar(&opts.skipReport, "s", false, "report non-regular files skipped, by type.")
+	flag.BoolVar(&opts.zero, "zero", false, "NUL-terminated lines of xxhsum file, without escaping.")
+	flag.BoolVar(&opts.zero, "z", false, "NUL-terminated lines of xxhsum file, without escaping.")
+	flag.StringVar(&filesFrom, "files-from", "", "hash files listed in FILE, or stdin if -.")
+	flag.StringVar(&filesFrom, "f", "", "hash files listed in FILE, or stdin if -.")
+	flag.BoolVar(&null, "null", false, "with --files-from, files are separated by NUL.")
+	flag.BoolVar(&null, "0", false, "with --files-from, files are separated by NUL.")
+	flag.StringVar(&opts.xxhsumFilepath, "xxhsum-filepath", "", "FILEPATH to file to append to.")
+	flag.StringVar(&opts.xxhsumFilepath, "x", "", "FILEPATH to file to append to.")
+	flag.BoolVar(&wait, "wait", false, "wait for other runs to release xxhsum file.")
+	flag.BoolVar(&wait, "w", false, "wait for other runs to release xxhsum file.")
+	flag.BoolVar(&opts.keepIndex, "index", false, "keep the index of sizes, mtimes and inodes up to date.")
+	flag.BoolVar(&opts.keepIndex, "I", false, "keep the index of sizes, mtimes and inodes up to date.")
+	flag.BoolVar(&opts.archives, "archives", false, "hash members of tar and zip archives.")
+	flag.BoolVar(&opts.archives, "A", false, "hash members of tar and zip archives.")
+	flag.BoolVar(&opts.prescan, "prescan", false, "total files and bytes to be hashed first, for progress and ETA.")
+	flag.BoolVar(&opts.prescan, "P", false, "total files and bytes to be hashed first, for progress and ETA.")
 	flag.Parse()
 
-	/*
-		Parsing PATH argument for given_path
-	*/
-	if flag.NArg() != 1 {
-		log.Fatalln(utils.RED + "PATH agrument missing or ambiguous" + utils.RESET)
+	opts.excludes, opts.includes = excludes, includes
+
+	if opts.jobs < 1 {
+		log.Fatalf(utils.RED+"--jobs must be at least 1, got %d"+utils.RESET, opts.jobs)
 	}
 
-	givenPath, err = utils.ArgParse(flag.Arg(0), verbose)
-	if err != nil {
+	if opts.algorithm, err = manifest.ParseAlgorithm(algorithm); err != nil {
 		log.Fatalf(utils.RED+"%s"+utils.RESET, err)
 	}
 
+	switch true {
+	case check && prune, check && opts.update, prune && opts.update, repair && (check || prune || opts.update):
+		log.Fatalln(utils.RED + "--check, --prune, --update and --repair are mutually exclusive" + utils.RESET)
+	case opts.dryRun && !prune && !opts.update && !repair:
+		log.Fatalln(utils.RED + "--dry-run requires --prune, --update or --repair" + utils.RESET)
+	case opts.rehash && !opts.update:
+		log.Fatalln(utils.RED + "--rehash requires --update" + utils.RESET)
+	case filesFrom != "" && (check || prune || repair):
+		log.Fatalln(utils.RED + "--files-from can't be used with --check, --prune or --repair" + utils.RESET)
+	case null && filesFrom == "":
+		log.Fatalln(utils.RED + "--null requires --files-from" + utils.RESET)
+	case strict && repair:
+		log.Fatalln(utils.RED + "--strict can't be used with --repair" + utils.RESET)
+	case opts.keepIndex && (check || prune || repair):
+		log.Fatalln(utils.RED + "--index can't be used with --check, --prune or --repair" + utils.RESET)
+	case opts.archives && repair:
+		log.Fatalln(utils.RED + "--archives can't be used with --repair" + utils.RESET)
+	case opts.prescan && (check || prune || repair):
+		log.Fatalln(utils.RED + "--prescan can't be used with --check, --prune or --repair" + utils.RESET)
+	}
+
+	/*
+		Parsing PATH arguments for given_paths
+	*/
+	switch true {
+	case (check || prune || repair) && flag.NArg() == 0 && opts.xxhsumFilepath != "":
+		// PATH is not needed to verify, prune or repair an explicitly given xxhsum file.
+	case filesFrom != "" && flag.NArg() == 0 && opts.xxhsumFilepath != "":
+		// PATH is not needed to hash listed files only.
+	case filesFrom != "" && flag.NArg() == 0:
+		log.Fatalln(utils.RED + "--xxhsum-filepath is required with --files-from and no PATH" + utils.RESET)
+	case flag.NArg() == 0:
+		log.Fatalln(utils.RED + "PATH agrument missing" + utils.RESET)
+	case flag.NArg() > 1 && opts.xxhsumFilepath == "":
+		log.Fatalln(utils.RED + "--xxhsum-filepath is required with more than one PATH" + utils.RESET)
+	default:
+		for _, arg := range flag.Args() {
+			if givenPath, err := utils.ArgParse(arg, opts.verbose); err != nil {
+				log.Fatalf(utils.RED+"%s"+utils.RESET, err)
+			} else {
+				givenPaths = append(givenPaths, givenPath)
+			}
+		}
+	}
+
 	/*
 		Parsing parameter xxhsum-filepath
 	*/
-	if xxhsumFilepath == "" {
-		xxhsumFilepath = givenPath + ".xxhsum"
-		if verbose {
-			log.Printf("--xxhsum-filepath defaulted to %s\n", xxhsumFilepath)
+	if opts.xxhsumFilepath == "" {
+		opts.xxhsumFilepath = givenPaths[0] + ".xxhsum"
+		if opts.verbose {
+			log.Printf("--xxhsum-filepath defaulted to %s\n", opts.xxhsumFilepath)
 		}
 	}
 
-	xxhsumFilepath, xxhsumFileExists, err = utils.ParamParse(xxhsumFilepath, verbose)
+	opts.xxhsumFilepath, xxhsumFileExists, err = utils.ParamParse(opts.xxhsumFilepath, opts.verbose)
 	if err != nil {
 		log.Fatalf(utils.RED+"%s"+utils.RESET, err)
 	}
@@ -230,55 +461,288 @@ func main() {
 		Doing the do
 	*/
 	if debug {
-		debugVariables(verbose, givenPath, xxhsumFilepath, xxhsumFileExists)
+		debugVariables(opts.verbose, givenPaths, opts.xxhsumFilepath, xxhsumFileExists)
+	}
+
+	if filesFrom != "" {
+		/*
+			Load list of files to hash
+		*/
+		if files, err = utils.LoadFileList(filesFrom, null); err != nil {
+			log.Fatalf(utils.RED+"%s"+utils.RESET, err)
+		}
+		if opts.verbose {
+			log.Printf("%d files listed in %s\n", len(files), filesFrom)
+		}
+	}
+
+	if (check || prune || repair) && !xxhsumFileExists {
+		log.Fatalf(utils.RED+"does not exist: %s"+utils.RESET, opts.xxhsumFilepath)
+	}
+
+	/*
+		Cancel the run on SIGINT or SIGTERM, keeping the work completed by then, also while waiting for the lock or loading
+	*/
+	ctx, stop = notifyInterrupt()
+	defer stop()
+
+	/*
+		Lock xxhsum_file for the whole run. Runs only reading it share the lock.
+	*/
+	if lock, err = acquireLock(ctx, opts.xxhsumFilepath, check || opts.dryRun, wait); err != nil {
+		if ctx.Err() != nil {
+			return reportInterrupted(ctx)
+		}
+		log.Printf(utils.RED+"%s"+utils.RESET, err)
+		return 1
+	}
+	if lock != nil {
+		defer lock.Release()
+	}
+
+	// Another run may have created xxhsum_file while this one waited.
+	if _, xxhsumFileExists, err = utils.ParamParse(opts.xxhsumFilepath, false); err != nil {
+		log.Printf(utils.RED+"%s"+utils.RESET, err)
+		return 1
 	}
 
 	if xxhsumFileExists {
 		/*
 			Load xxhsum_file to dictionary
 		*/
-		s = spinner.New(spinner.CharSets[14], 1000*time.Millisecond, spinner.WithWriter(os.Stderr),
-			spinner.WithSuffix(" Loading existing xxhsum file"),
-			spinner.WithFinalMSG(fmt.Sprintf("Loading existing %s xxhsum file complete\n", xxhsumFilepath)))
-		s.Start()
+		loading := newStderrProgress(opts.verbose)
+		loading.beginLoad()
+		loading.start()
 
-		dict, err = utils.LoadXXHSumFile(xxhsumFilepath, bsdStyle)
+		dict, report, err = manifest.LoadContext(ctx, opts.xxhsumFilepath, opts.zero)
 
-		s.Stop()
+		if ctx.Err() != nil {
+			loading.end(fmt.Sprintf("Loading existing %s xxhsum file interrupted", opts.xxhsumFilepath))
+		} else {
+			loading.end(fmt.Sprintf("Loading existing %s xxhsum file complete", opts.xxhsumFilepath))
+		}
 
+		if ctx.Err() != nil {
+			return reportInterrupted(ctx)
+		}
 		if err != nil {
-			log.Fatalf(utils.RED+"%s"+utils.RESET, err)
+			log.Printf(utils.RED+"%s"+utils.RESET, err)
+			return 1
 		}
 
-		if verbose {
+		if opts.verbose {
+			log.Printf("%s has %d GNU-style and %d BSD-style lines\n", opts.xxhsumFilepath, report.GNULines, report.BSDLines)
 			/*
 				Dump xxhsum_file dictionary
 			*/
-			utils.DumpXXHSumDict(dict)
+			for _, entry := range dict.Entries() {
+				log.Printf(utils.BLUE+"DUMP"+utils.RESET+" %s  %s\n", entry.Checksum(), entry.Path)
+			}
 		}
-	} else {
-		// Create a GNU-style file with heading comment.
-		if !bsdStyle {
-			appendToFile(xxhsumFilepath, "# XXH64 hashes https://xxhash.com/\n")
-			appendToFile(xxhsumFilepath, "# To verify use xxhsum --check --quiet FILEPATH\n")
+
+		if report.Unterminated && !report.Mismatched {
+			log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %s ends with an incomplete line, e.g. after an interrupted append; ignoring it, and cutting it off if appending\n", opts.xxhsumFilepath)
+		}
+		if opts.verbose || strict {
+			for _, diagnostic := range report.Diagnostics {
+				log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %s: %s\n", opts.xxhsumFilepath, diagnostic)
+			}
+		}
+		if report.Malformed > 0 && !repair {
+			log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %s has %d malformed lines; use --repair to remove them\n", opts.xxhsumFilepath, report.Malformed)
+		}
+		if report.Conflicting > 0 {
+			log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %s lists %d file names again with another hash; the last one is used\n", opts.xxhsumFilepath, report.Conflicting)
+		}
+		if report.Duplicate > 0 && opts.verbose {
+			log.Printf("%s lists %d file names again with the same hash\n", opts.xxhsumFilepath, report.Duplicate)
+		}
+		if strict && report.HasProblems() {
+			log.Printf(utils.RED+"%s has %d malformed, %d conflicting and %d duplicate lines; --strict given"+utils.RESET,
+				opts.xxhsumFilepath, report.Malformed, report.Conflicting, report.Duplicate)
+			return 1
+		}
+		// Refuse to read, let alone append to or rewrite, lines terminated the other way.
+		if err = checkTerminator(report.Mismatched, opts.zero, opts.xxhsumFilepath); err != nil {
+			log.Printf(utils.RED+"%s"+utils.RESET, err)
+			return 1
+		}
+	}
+
+	if repair {
+		/*
+			Remove malformed lines, and the incomplete last one
+		*/
+		if opts.dryRun {
+			incomplete := 0
+			if report.Unterminated {
+				incomplete = 1
+			}
+			log.Printf("%d malformed lines would be removed from %s; dry run, nothing changed\n", report.Malformed+incomplete, opts.xxhsumFilepath)
+			return 0
+		}
+		removed, err := manifest.Repair(opts.xxhsumFilepath, opts.zero)
+		if err != nil {
+			log.Printf(utils.RED+"%s"+utils.RESET, err)
+			return 1
+		}
+		for _, line := range removed {
+			fmt.Printf("%q: MALFORMED\n", line)
+		}
+		log.Printf("%d malformed lines removed from %s\n", len(removed), opts.xxhsumFilepath)
+		return 0
+	}
+
+	if check {
+		/*
+			Verify dictionary against the filesystem
+		*/
+		ok, failed, missing := checkDict(ctx, dict, opts)
+
+		if ctx.Err() != nil {
+			log.Printf("%d OK, %d FAILED, %d MISSING in %s before interrupt\n", ok, failed, missing, opts.xxhsumFilepath)
+			return reportInterrupted(ctx)
+		}
+		log.Printf("%d OK, %d FAILED, %d MISSING in %s\n", ok, failed, missing, opts.xxhsumFilepath)
+		if failed+missing > 0 {
+			log.Printf(utils.RED+"WARNING"+utils.RESET+" %d of %d computed checksums did NOT match\n", failed+missing, dict.Len())
+			return 1
+		}
+		return 0
+	}
+
+	if prune {
+		/*
+			Remove lines of files missing from the filesystem
+		*/
+		missing, err := manifest.FindMissing(ctx, dict, filepath.Dir(opts.xxhsumFilepath), opts.archives)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("%v\n", err)
+		}
+		for _, rel_path := range missing {
+			fmt.Printf("%s: MISSING\n", rel_path)
+		}
+
+		if ctx.Err() != nil {
+			log.Printf("%d entries found missing before interrupt; nothing pruned from %s\n", len(missing), opts.xxhsumFilepath)
+			return reportInterrupted(ctx)
+		}
+
+		if opts.dryRun {
+			log.Printf("%d entries would be pruned from %s; dry run, nothing changed\n", len(missing), opts.xxhsumFilepath)
+			return 0
+		}
+		if len(missing) > 0 {
+			if err = manifest.Remove(opts.xxhsumFilepath, opts.zero, missing...); err != nil {
+				log.Printf(utils.RED+"%s"+utils.RESET, err)
+				return 1
+			}
+		}
+		log.Printf("%d entries pruned from %s\n", len(missing), opts.xxhsumFilepath)
+		return 0
+	}
+
+	// Refuse to append lines of other style than existing ones.
+	if err = checkStyle(report.Style(), opts.bsdStyle, opts.xxhsumFilepath); err != nil {
+		log.Printf(utils.RED+"%s"+utils.RESET, err)
+		return 1
+	}
+
+	if opts.update || opts.keepIndex {
+		/*
+			Load index of sizes, mtimes and inodes, keeping only files still listed
+		*/
+		if index, err = manifest.LoadIndex(manifest.IndexFilepath(opts.xxhsumFilepath)); err != nil {
+			log.Printf(utils.RED+"%s"+utils.RESET, err)
+			return 1
+		}
+		for rel_path := range index {
+			if _, ok := dict.Lookup(rel_path); !ok {
+				delete(index, rel_path)
+			}
+		}
+	}
+
+	if !opts.dryRun {
+		/*
+			Open xxhsum_file for appending for the whole run
+		*/
+		appender, err = manifest.NewAppender(opts.xxhsumFilepath, opts.style(), opts.zero)
+		if err != nil {
+			log.Printf(utils.RED+"%s"+utils.RESET, err)
+			return 1
+		}
+
+		if !xxhsumFileExists {
+			// Create a GNU-style file with heading comment. BSD-style and NUL-terminated files get none.
+			appender.WriteHeader(opts.algorithm)
 		}
 	}
 
 	/*
-	   Search given_path against dictionary
+	   Search given_path against dictionary, showing progress on a terminal line, or in log lines if there is none
 	*/
-	s = spinner.New(spinner.CharSets[14], 1000*time.Millisecond, spinner.WithWriter(os.Stderr),
-		spinner.WithSuffix(" Searching and appending new xxhashes to xxhsum file"),
-		spinner.WithFinalMSG(fmt.Sprintf("Searching %s and appending new xxhashes to %s xxhsum file complete\n", givenPath, xxhsumFilepath)))
-	if !verbose {
-		s.Start()
+	p = newStderrProgress(opts.verbose)
+	p.start()
+
+	found = searchDir(ctx, manifest.DirRoots(givenPaths...), files, dict, index, appender, p, opts)
+
+	if ctx.Err() != nil {
+		p.finish(fmt.Sprintf("Searching %s and appending new xxhashes to %s xxhsum file interrupted", describeSources(givenPaths, filesFrom), opts.xxhsumFilepath))
+	} else {
+		p.finish(fmt.Sprintf("Searching %s and appending new xxhashes to %s xxhsum file complete", describeSources(givenPaths, filesFrom), opts.xxhsumFilepath))
 	}
 
-	i = searchDir(givenPath, dict, xxhsumFilepath, bsdStyle, verbose)
+	if appender != nil {
+		// Flush lines of files hashed, also when interrupted.
+		if err = appender.Close(); err != nil {
+			log.Printf(utils.RED+"%s"+utils.RESET, err)
+			return 1
+		}
+		if ctx.Err() != nil {
+			log.Printf("%d xxhashes appended to %s before interrupt\n", found.appended, opts.xxhsumFilepath)
+		} else {
+			log.Printf("%d xxhashes appended to %s\n", found.appended, opts.xxhsumFilepath)
+		}
+	}
 
-	if !verbose {
-		s.Stop()
+	if opts.skipReport {
+		reportSkipped(found.skipped)
 	}
 
-	log.Printf("%d xxhashes appended to %s\n", i, xxhsumFilepath)
+	if found.modified > 0 {
+		log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %d listed files modified since hashed; use --update to re-hash them\n", found.modified)
+	}
+
+	if opts.update {
+		/*
+			Replace lines of changed files
+		*/
+		if opts.dryRun {
+			log.Printf("%d changed entries would be replaced in %s; dry run, nothing changed\n", found.changed.Len(), opts.xxhsumFilepath)
+		} else {
+			if found.changed.Len() > 0 {
+				if err = manifest.Replace(opts.xxhsumFilepath, opts.zero, found.changed.Entries()...); err != nil {
+					log.Printf(utils.RED+"%s"+utils.RESET, err)
+					return 1
+				}
+			}
+			log.Printf("%d changed entries replaced in %s\n", found.changed.Len(), opts.xxhsumFilepath)
+		}
+	}
+
+	if index != nil && !opts.dryRun {
+		/*
+			Save the index
+		*/
+		if err = manifest.SaveIndex(manifest.IndexFilepath(opts.xxhsumFilepath), index); err != nil {
+			log.Printf(utils.RED+"%s"+utils.RESET, err)
+			return 1
+		}
+	}
+
+	if ctx.Err() != nil {
+		return reportInterrupted(ctx)
+	}
+	return 0
 }
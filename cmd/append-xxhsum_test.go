@@ -1,95 +1,705 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/manifest"
 )
 
-func Test_appendToFile(t *testing.T) {
+// Outputs a manifest of `checksums`, as GNU-style lines have them, by file name. Unrecognised ones are kept.
+func newDict(checksums map[string]string) *manifest.Manifest {
+	dict := manifest.New()
+	for path, checksum := range checksums {
+		entry, _ := manifest.NewEntry(path, checksum)
+		dict.Add(entry)
+	}
+	return dict
+}
+
+func Test_emitLine(t *testing.T) {
+	type args struct {
+		entry   manifest.Entry
+		verbose bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{"QUIET", args{manifest.Entry{Path: "a", Hash: "91a7667cd2256abd", Algorithm: manifest.XXH64}, false}, 1},
+		{"VERBOSE", args{manifest.Entry{Path: "a", Hash: "91a7667cd2256abd", Algorithm: manifest.XXH64}, true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := options{verbose: tt.args.verbose}
+			appender, err := manifest.NewAppender(filepath.Join(t.TempDir(), "test3.xx_append"), opts.style(), opts.zero)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := emitLine(appender, tt.args.entry, opts); got != tt.want {
+				t.Errorf("emitLine() = %v, want %v", got, tt.want)
+			}
+			if err := appender.Close(); err != nil {
+				t.Errorf("Appender.Close() error = %v", err)
+			}
+		})
+	}
+}
+
+func Test_debugVariables(t *testing.T) {
+	type args struct {
+		verbose          bool
+		givenPaths       []string
+		xxhsumFilepath   string
+		xxhsumFileExists bool
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{"DUMMY", args{true, []string{"/home/lukasz"}, "/home/lukasz/test1.xxhsum", true}},
+		{"MANY_PATHS", args{false, []string{"/home/lukasz/Photos", "/home/lukasz/Videos"}, "/home/lukasz/archive.xxhsum", false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			debugVariables(tt.args.verbose, tt.args.givenPaths, tt.args.xxhsumFilepath, tt.args.xxhsumFileExists)
+		})
+	}
+}
+
+func Test_checkDict(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.txt"), []byte("Lorem ipsum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.txt"), []byte("Lorem ipsum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	good, err := manifest.HashFile(filepath.Join(dir, "good.txt"), manifest.XXH64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good3, err := manifest.HashFile(filepath.Join(dir, "good.txt"), manifest.XXH3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good128, err := manifest.HashFile(filepath.Join(dir, "good.txt"), manifest.XXH128)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		dict           map[string]string
+		xxhsumFilepath string
+		verbose        bool
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantOk      int
+		wantFailed  int
+		wantMissing int
+	}{
+		{"EMPTY", args{map[string]string{}, filepath.Join(dir, "test.xxhsum"), true}, 0, 0, 0},
+		{"OK", args{map[string]string{"good.txt": good}, filepath.Join(dir, "test.xxhsum"), true}, 1, 0, 0},
+		{"OK_UPPERCASE", args{map[string]string{"good.txt": strings.ToUpper(good)}, filepath.Join(dir, "test.xxhsum"), false}, 1, 0, 0},
+		{"FAILED", args{map[string]string{"bad.txt": "0000000000000000"}, filepath.Join(dir, "test.xxhsum"), false}, 0, 1, 0},
+		{"MISSING", args{map[string]string{"gone.txt": good}, filepath.Join(dir, "test.xxhsum"), false}, 0, 0, 1},
+		{"MIXED", args{map[string]string{"good.txt": good, "bad.txt": "0000000000000000", "gone.txt": good}, filepath.Join(dir, "test.xxhsum"), true}, 1, 1, 1},
+		{"OK_XXH3", args{map[string]string{"good.txt": "XXH3_" + good3}, filepath.Join(dir, "test.xxhsum"), true}, 1, 0, 0},
+		{"OK_XXH128", args{map[string]string{"good.txt": good128}, filepath.Join(dir, "test.xxhsum"), true}, 1, 0, 0},
+		{"FAILED_XXH3_AS_XXH64", args{map[string]string{"good.txt": good3}, filepath.Join(dir, "test.xxhsum"), true}, 0, 1, 0},
+		{"FAILED_UNRECOGNISED", args{map[string]string{"good.txt": "123"}, filepath.Join(dir, "test.xxhsum"), true}, 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOk, gotFailed, gotMissing := checkDict(context.Background(), newDict(tt.args.dict), options{xxhsumFilepath: tt.args.xxhsumFilepath, verbose: tt.args.verbose})
+			if gotOk != tt.wantOk || gotFailed != tt.wantFailed || gotMissing != tt.wantMissing {
+				t.Errorf("checkDict() = %v, %v, %v, want %v, %v, %v", gotOk, gotFailed, gotMissing, tt.wantOk, tt.wantFailed, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func Test_searchDir(t *testing.T) {
+	type args struct {
+		dict      map[string]string
+		bsdStyle  bool
+		jobs      int
+		keepOrder bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want int
+	}{
+		{"SINGLE_JOB", args{map[string]string{}, false, 1, false}, 20},
+		{"MANY_JOBS", args{map[string]string{}, false, 8, false}, 20},
+		{"MANY_JOBS_KEEP_ORDER", args{map[string]string{}, true, 8, true}, 20},
+		{"SKIP_EXISTING", args{map[string]string{"data/f00": "0", "data/sub/f19": "0"}, false, 4, true}, 18},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "data")
+			if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			for n := 0; n < 20; n++ {
+				name := filepath.Join(root, fmt.Sprintf("f%02d", n))
+				if n >= 10 {
+					name = filepath.Join(root, "sub", fmt.Sprintf("f%02d", n))
+				}
+				if err := os.WriteFile(name, []byte(strings.Repeat("x", n*1000)), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+
+			opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, bsdStyle: tt.args.bsdStyle, jobs: tt.args.jobs, keepOrder: tt.args.keepOrder}
+			appender, err := manifest.NewAppender(xxhsumFilepath, opts.style(), opts.zero)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := searchDir(context.Background(), manifest.DirRoots(root), nil, newDict(tt.args.dict), nil, appender, nil, opts); got.appended != tt.want {
+				t.Errorf("searchDir() = %v, want %v", got.appended, tt.want)
+			}
+			if err := appender.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			dict, _, err := manifest.Load(xxhsumFilepath, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dict.Len() != tt.want {
+				t.Errorf("searchDir() wrote %v entries, want %v", dict.Len(), tt.want)
+			}
+
+			if tt.args.keepOrder {
+				content, err := os.ReadFile(xxhsumFilepath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				paths := []string{}
+				for _, line := range strings.Split(strings.TrimSuffix(string(content), "\n"), "\n") {
+					paths = append(paths, line[strings.IndexAny(line, "*(")+1:])
+				}
+				if !sort.StringsAreSorted(paths) {
+					t.Errorf("searchDir() lines not in walk order: %v", paths)
+				}
+			}
+		})
+	}
+}
+
+func Test_searchDir_fs(t *testing.T) {
+	dir := t.TempDir()
+	fsys := fstest.MapFS{
+		".xxhsumignore":  {Data: []byte("*.tmp\n")},
+		"a":              {Data: []byte("Lorem ipsum\n")},
+		"b.tmp":          {Data: []byte("x")},
+		"sub/c":          {Data: []byte("Lorem ipsum\n")},
+		"sub/d e":        {Data: []byte("")},
+		"sub/deeper/f":   {Data: []byte("y")},
+		"sub/deeper/g.o": {Data: []byte("z")},
+	}
+	xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+
+	appender, err := manifest.NewAppender(xxhsumFilepath, manifest.GNU, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, excludes: []string{"*.o"}}
+	roots := []manifest.Root{{FS: fsys, Dir: filepath.Join(dir, "data")}}
+	if got := searchDir(context.Background(), roots, nil, newDict(map[string]string{"data/sub/c": "91a7667cd2256abd"}), nil, appender, nil, opts); got.appended != 4 {
+		t.Errorf("searchDir() = %v, want %v", got.appended, 4)
+	}
+	if err := appender.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	dict, _, err := manifest.Load(xxhsumFilepath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		filepath.Join("data", ".xxhsumignore"):      "",
+		filepath.Join("data", "a"):                  "91a7667cd2256abd",
+		filepath.Join("data", "sub", "d e"):         "ef46db3751d8e999",
+		filepath.Join("data", "sub", "deeper", "f"): "",
+	}
+	for key, hash := range want {
+		if got, ok := dict.Lookup(key); !ok || (hash != "" && got.Checksum() != hash) {
+			t.Errorf("searchDir() wrote %v = %v, want %v", key, got.Checksum(), hash)
+		}
+	}
+	if dict.Len() != len(want) {
+		t.Errorf("searchDir() wrote %v, want keys of %v", dict.Paths(), want)
+	}
+}
+
+func Test_recordFound(t *testing.T) {
+	var (
+		entry    manifest.Entry = manifest.Entry{Path: "a", Hash: "91a7667cd2256abd", Algorithm: manifest.XXH64}
+		previous manifest.Entry = manifest.Entry{Path: "a", Hash: "0000000000000000", Algorithm: manifest.XXH64}
+	)
+
 	type args struct {
-		filename string
-		content  string
+		finding manifest.Finding
+		err     error
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantContent  string
+		wantChanged  []string
+		wantModified int
+	}{
+		{"NEW", args{manifest.FoundNew, nil}, "91a7667cd2256abd *a\n", []string{}, 0},
+		{"CHANGED", args{manifest.FoundChanged, nil}, "", []string{"a"}, 0},
+		{"UNCHANGED", args{manifest.FoundUnchanged, nil}, "", []string{}, 0},
+		{"FAILED", args{manifest.FoundUnreadable, errors.New("read error")}, "", []string{}, 0},
+		{"CANCELLED", args{manifest.FoundUnreadable, context.Canceled}, "", []string{}, 0},
+		{"MODIFIED", args{manifest.FoundModified, nil}, "", []string{}, 1},
+		{"LISTED", args{manifest.FoundListed, nil}, "", []string{}, 0},
+		{"UNRECOGNISED", args{manifest.FoundUnrecognised, nil}, "", []string{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xxhsumFilepath := filepath.Join(t.TempDir(), "test.xxhsum")
+			opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, verbose: true}
+			appender, err := manifest.NewAppender(xxhsumFilepath, opts.style(), opts.zero)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			f := manifest.Found{Finding: tt.args.finding, Path: "a", Previous: previous, Err: tt.args.err}
+			if tt.args.err == nil {
+				f.Entry = entry
+			}
+			found := searchResult{changed: manifest.New(), skipped: make(map[string]int)}
+			recordFound(&found, f, appender, nil, opts)
+			if err := appender.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if content, err := os.ReadFile(xxhsumFilepath); err != nil {
+				t.Fatal(err)
+			} else if string(content) != tt.wantContent {
+				t.Errorf("recordFound() wrote %q, want %q", content, tt.wantContent)
+			}
+			if got := found.changed.Paths(); !reflect.DeepEqual(got, tt.wantChanged) {
+				t.Errorf("recordFound() changed = %v, want %v", got, tt.wantChanged)
+			}
+			if found.modified != tt.wantModified {
+				t.Errorf("recordFound() modified = %v, want %v", found.modified, tt.wantModified)
+			}
+		})
+	}
+}
+
+func Test_checkStyle(t *testing.T) {
+	type args struct {
+		style    manifest.Style
+		bsdStyle bool
 	}
 	tests := []struct {
 		name    string
 		args    args
 		wantErr bool
 	}{
-		{"FILE", args{"/home/lukasz/Code/golang/append-xxhsum/tst/test3.xx_append", "Lorem ipsum\n"}, false},
+		{"NONE_GNU", args{"", false}, false},
+		{"NONE_BSD", args{"", true}, false},
+		{"GNU_GNU", args{manifest.GNU, false}, false},
+		{"GNU_BSD", args{manifest.GNU, true}, true},
+		{"BSD_BSD", args{manifest.BSD, true}, false},
+		{"BSD_GNU", args{manifest.BSD, false}, true},
+		{"MIXED_GNU", args{manifest.Mixed, false}, false},
+		{"MIXED_BSD", args{manifest.Mixed, true}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := appendToFile(tt.args.filename, tt.args.content); (err != nil) != tt.wantErr {
-				t.Errorf("appendToFile() error = %v, wantErr %v", err, tt.wantErr)
+			if err := checkStyle(tt.args.style, tt.args.bsdStyle, "test.xxhsum"); (err != nil) != tt.wantErr {
+				t.Errorf("checkStyle() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func Test_calculateXXHash(t *testing.T) {
+func Test_checkTerminator(t *testing.T) {
 	type args struct {
-		filePath string
+		mismatched bool
+		zero       bool
 	}
 	tests := []struct {
 		name    string
 		args    args
-		want    string
 		wantErr bool
 	}{
-		{"EXISTS", args{"/home/lukasz/Code/golang/append-xxhsum/tst/test1.xxhsum"}, "1b4378db293122d8", false},
-		{"DOES_NOT_EXIST", args{"/home/lukasz/Code/golang/append-xxhsum/tst/tes.xxhm"}, "", true},
+		{"NEWLINE", args{false, false}, false},
+		{"ZERO", args{false, true}, false},
+		{"NUL_IN_NEWLINE_MODE", args{true, false}, true},
+		{"NEWLINE_IN_ZERO_MODE", args{true, true}, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := calculateXXHash(tt.args.filePath)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("calculateXXHash() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
-			if got != tt.want {
-				t.Errorf("calculateXXHash() = %v, want %v", got, tt.want)
+			if err := checkTerminator(tt.args.mismatched, tt.args.zero, "test.xxhsum"); (err != nil) != tt.wantErr {
+				t.Errorf("checkTerminator() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
 	}
 }
 
-func Test_debugVariables(t *testing.T) {
+func Test_searchDir_update(t *testing.T) {
 	type args struct {
-		verbose          bool
-		givenPath        string
-		xxhsumFilepath   string
-		xxhsumFileExists bool
+		modify bool
+		touch  bool
+		rehash bool
+		dryRun bool
 	}
 	tests := []struct {
-		name string
-		args args
+		name        string
+		args        args
+		wantChanged []string
 	}{
-		{"DUMMY", args{true, "/home/lukasz", "/home/lukasz/test1.xxhsum", true}},
+		{"UNCHANGED", args{false, false, false, false}, []string{}},
+		{"UNCHANGED_REHASH", args{false, false, true, false}, []string{}},
+		{"TOUCHED", args{false, true, false, false}, []string{}},
+		{"MODIFIED", args{true, true, false, false}, []string{"data/a"}},
+		{"MODIFIED_SAME_SIZE_AND_MTIME", args{true, false, false, false}, []string{}},
+		{"MODIFIED_SAME_SIZE_AND_MTIME_REHASH", args{true, false, true, false}, []string{"data/a"}},
+		{"MODIFIED_DRY_RUN", args{true, true, false, true}, []string{"data/a"}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			debugVariables(tt.args.verbose, tt.args.givenPath, tt.args.xxhsumFilepath, tt.args.xxhsumFileExists)
+			dir := t.TempDir()
+			root := filepath.Join(dir, "data")
+			if err := os.MkdirAll(root, 0755); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(root, "a")
+			if err := os.WriteFile(path, []byte("Lorem ipsum\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+			checksum, err := manifest.HashFile(path, manifest.XXH64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dict := newDict(map[string]string{"data/a": checksum})
+			index := map[string]manifest.FileMeta{"data/a": {Size: 12, ModTime: mtime.UnixNano()}}
+
+			if tt.args.modify {
+				if err := os.WriteFile(path, []byte("Lorem IPSUM\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.args.touch {
+				mtime = mtime.Add(time.Hour)
+			}
+			if err := os.Chtimes(path, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+
+			xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+			appender, err := manifest.NewAppender(xxhsumFilepath, manifest.GNU, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer appender.Close()
+
+			opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, update: true, rehash: tt.args.rehash, dryRun: tt.args.dryRun}
+			got := searchDir(context.Background(), manifest.DirRoots(root), nil, dict, index, appender, nil, opts)
+
+			if got.appended != 0 {
+				t.Errorf("searchDir() appended = %v, want %v", got.appended, 0)
+			}
+			if keys := got.changed.Paths(); !reflect.DeepEqual(keys, tt.wantChanged) {
+				t.Errorf("searchDir() changed = %v, want %v", keys, tt.wantChanged)
+			}
+			if index["data/a"].ModTime != mtime.UnixNano() {
+				t.Errorf("searchDir() index mtime = %v, want %v", index["data/a"].ModTime, mtime.UnixNano())
+			}
 		})
 	}
 }
 
-func Test_calculateLine(t *testing.T) {
-	type args struct {
-		bsdStyle bool
-		relPath  string
-		checksum string
+func Test_searchDir_index(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "data")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("Lorem ipsum\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	infoA, err := os.Stat(filepath.Join(root, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	infoB, err := os.Stat(filepath.Join(root, "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// `a` is unchanged since indexed, `b` has grown since, and `c` is new.
+	dict := newDict(map[string]string{"data/a": "1111111111111111", "data/b": "2222222222222222"})
+	index := map[string]manifest.FileMeta{"data/a": manifest.NewFileMeta(infoA), "data/b": manifest.NewFileMeta(infoB)}
+	index["data/b"] = manifest.FileMeta{Size: 1, ModTime: index["data/b"].ModTime, Inode: index["data/b"].Inode}
+
+	xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+	appender, err := manifest.NewAppender(xxhsumFilepath, manifest.GNU, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer appender.Close()
+
+	before := time.Now().UnixNano()
+	opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, keepIndex: true}
+	got := searchDir(context.Background(), manifest.DirRoots(root), nil, dict, index, appender, nil, opts)
+
+	if got.appended != 1 || got.modified != 1 || got.changed.Len() != 0 {
+		t.Errorf("searchDir() appended = %v, modified = %v, changed = %v, want 1, 1 and none", got.appended, got.modified, got.changed.Paths())
+	}
+	if index["data/b"].Size != 1 {
+		t.Errorf("searchDir() index of modified file = %+v, want it kept until re-hashed", index["data/b"])
+	}
+	if meta, ok := index["data/c"]; !ok || meta.Size != 12 || meta.HashedAt < before {
+		t.Errorf("searchDir() index of new file = %+v, want size 12 and hashed after %v", meta, before)
+	}
+}
+
+func Test_searchDir_upstream(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "data")
+	for _, name := range []string{"a.txt", "sub/b.txt", "sub/c.txt", "d.txt"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
 	}
+
+	// Keys spelled as upstream xxhsum and hand-edited files have them.
+	xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+	content := "0000000000000001  ./data/a.txt\n0000000000000002 *data//sub/b.txt\nXXH64 (./data/sub/../sub/c.txt/) = 0000000000000003\n"
+	if err := os.WriteFile(xxhsumFilepath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, _, err := manifest.Load(xxhsumFilepath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	appender, err := manifest.NewAppender(xxhsumFilepath, manifest.GNU, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer appender.Close()
+
+	opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, verbose: true}
+	if got := searchDir(context.Background(), manifest.DirRoots(root), nil, dict, nil, appender, nil, opts); got.appended != 1 {
+		t.Errorf("searchDir() = %v, want %v", got.appended, 1)
+	}
+}
+
+func Test_searchDir_escaped(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "data")
+	names := []string{"new\nline", "back\\slash", "carriage\rreturn", "plain"}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, style := range []struct{ bsdStyle, zero bool }{{false, false}, {true, false}, {false, true}, {true, true}} {
+		xxhsumFilepath := filepath.Join(dir, fmt.Sprintf("data-%t-%t.xxhsum", style.bsdStyle, style.zero))
+		opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, bsdStyle: style.bsdStyle, zero: style.zero, jobs: 2}
+
+		appender, err := manifest.NewAppender(xxhsumFilepath, opts.style(), opts.zero)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := searchDir(context.Background(), manifest.DirRoots(root), nil, manifest.New(), nil, appender, nil, opts); got.appended != len(names) {
+			t.Errorf("searchDir() = %v, want %v", got.appended, len(names))
+		}
+		if err := appender.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		// File names round-trip exactly, so they are all found and verified on the next run.
+		dict, _, err := manifest.Load(xxhsumFilepath, style.zero)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			if _, ok := dict.Lookup(filepath.Join("data", name)); !ok {
+				t.Errorf("Load() misses %q in %v", name, dict.Paths())
+			}
+		}
+		if ok, failed, missing := checkDict(context.Background(), dict, opts); ok != len(names) || failed+missing != 0 {
+			t.Errorf("checkDict() = %v, %v, %v, want %v, 0, 0", ok, failed, missing, len(names))
+		}
+	}
+}
+
+func Test_searchDir_roots(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Photos/a.jpg", "Photos/2020/b.jpg", "Videos/c.mp4", "Documents/d.txt"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
 	tests := []struct {
-		name string
-		args args
-		want string
+		name     string
+		roots    []string
+		keys     []string
+		wantKeys []string
+	}{
+		{"MANY", []string{"Photos", "Videos", "Documents"}, nil,
+			[]string{"Documents/d.txt", "Photos/2020/b.jpg", "Photos/a.jpg", "Videos/c.mp4"}},
+		{"OVERLAPPING", []string{"Photos", "Photos/2020"}, nil,
+			[]string{"Photos/2020/b.jpg", "Photos/a.jpg"}},
+		{"SKIP_EXISTING", []string{"Photos", "Videos"}, []string{"Videos/c.mp4"},
+			[]string{"Photos/2020/b.jpg", "Photos/a.jpg", "Videos/c.mp4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xxhsumFilepath := filepath.Join(dir, tt.name+".xxhsum")
+			dict := map[string]string{}
+			for _, key := range tt.keys {
+				dict[key] = "0000000000000000"
+			}
+			roots := []string{}
+			for _, root := range tt.roots {
+				roots = append(roots, filepath.Join(dir, root))
+			}
+
+			appender, err := manifest.NewAppender(xxhsumFilepath, manifest.GNU, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 4, keepOrder: true}
+			if got := searchDir(context.Background(), manifest.DirRoots(roots...), nil, newDict(dict), nil, appender, nil, opts); got.appended != len(tt.wantKeys)-len(tt.keys) {
+				t.Errorf("searchDir() = %v, want %v", got.appended, len(tt.wantKeys)-len(tt.keys))
+			}
+			if err := appender.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, _, err := manifest.Load(xxhsumFilepath, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keys := append(got.Paths(), tt.keys...)
+			sort.Strings(keys)
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("searchDir() wrote %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func Test_searchDir_files(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"data/a.txt", "data/b.txt", "data/sub/c.txt", "other/d.txt"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+	if err := os.WriteFile(xxhsumFilepath, []byte("0000000000000000 *data/b.txt\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict, _, err := manifest.Load(xxhsumFilepath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Listed paths are relative to the working directory.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join(dir, "data")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	files := []string{
+		"a.txt",                              // New.
+		"./a.txt",                            // Listed twice.
+		"b.txt",                              // Already listed in `dict`.
+		"sub",                                // Directory.
+		"gone.txt",                           // Missing.
+		"../data.xxhsum",                     // The xxhsum file itself.
+		filepath.Join(dir, "other", "d.txt"), // New, absolute.
+	}
+
+	appender, err := manifest.NewAppender(xxhsumFilepath, manifest.GNU, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, keepOrder: true}
+	if got := searchDir(context.Background(), nil, files, dict, nil, appender, nil, opts); got.appended != 2 {
+		t.Errorf("searchDir() = %v, want %v", got.appended, 2)
+	}
+	if err := appender.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, _, err := manifest.Load(xxhsumFilepath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys, want := got.Paths(), []string{"data/a.txt", "data/b.txt", "other/d.txt"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("searchDir() wrote %v, want %v", keys, want)
+	}
+}
+
+func Test_describeSources(t *testing.T) {
+	tests := []struct {
+		name       string
+		givenPaths []string
+		filesFrom  string
+		want       string
 	}{
-		{"BSD", args{true, "/home/lukasz", "123567890123456"}, "XXH64 (/home/lukasz) = 123567890123456\n"},
-		{"GNU", args{false, "/home/lukasz", "123567890123456"}, "123567890123456 */home/lukasz\n"},
+		{"PATHS", []string{"/a", "/b"}, "", "/a, /b"},
+		{"FILES_FROM", nil, "list.txt", "files listed in list.txt"},
+		{"STDIN", []string{"/a"}, "-", "/a, files listed in stdin"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := calculateLine(tt.args.bsdStyle, tt.args.relPath, tt.args.checksum); got != tt.want {
-				t.Errorf("calculateLine() = %v, want %v", got, tt.want)
+			if got := describeSources(tt.givenPaths, tt.filesFrom); got != tt.want {
+				t.Errorf("describeSources() = %v, want %v", got, tt.want)
 			}
 		})
 	}
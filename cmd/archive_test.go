@@ -0,0 +1,186 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/manifest"
+)
+
+// Outputs keys of the map `m` in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Writes the tar archive at `path` holding `members` by name, gzip-compressed if its name says so.
+func writeTar(t *testing.T, path string, members map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var w io.Writer = file
+	if filepath.Ext(path) == ".gz" || filepath.Ext(path) == ".tgz" {
+		gz := gzip.NewWriter(file)
+		defer gz.Close()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	for _, name := range sortedKeys(members) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(members[name])), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(members[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Writes the zip archive at `path` holding `members` by name.
+func writeZip(t *testing.T, path string, members map[string]string) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	defer zw.Close()
+	for _, name := range sortedKeys(members) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(members[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Creates the tree of a plain file and three archives under the `root` directory.
+func writeArchiveTree(t *testing.T, root string) {
+	if err := os.MkdirAll(filepath.Join(root, "old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a"), []byte("Lorem ipsum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeTar(t, filepath.Join(root, "snapshot.tar"), map[string]string{"inner/path.txt": "Lorem ipsum\n", "inner/b.tmp": "x"})
+	writeTar(t, filepath.Join(root, "old", "snapshot.tar.gz"), map[string]string{"./c": ""})
+	writeZip(t, filepath.Join(root, "snapshot.zip"), map[string]string{"inner/": "", "inner/path.txt": "Lorem ipsum\n", "d": "y"})
+}
+
+func Test_searchDir_archives(t *testing.T) {
+	type args struct {
+		archives bool
+		excludes []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want map[string]string
+	}{
+		{"ARCHIVES", args{true, nil}, map[string]string{
+			"data/a":                           "91a7667cd2256abd",
+			"data/snapshot.tar/inner/path.txt": "91a7667cd2256abd",
+			"data/snapshot.tar/inner/b.tmp":    "",
+			"data/old/snapshot.tar.gz/c":       "ef46db3751d8e999",
+			"data/snapshot.zip/inner/path.txt": "91a7667cd2256abd",
+			"data/snapshot.zip/d":              "",
+		}},
+		{"ARCHIVES_EXCLUDED", args{true, []string{"*.tmp", "snapshot.zip/inner/", "*.gz"}}, map[string]string{
+			"data/a":                           "91a7667cd2256abd",
+			"data/snapshot.tar/inner/path.txt": "91a7667cd2256abd",
+			"data/snapshot.zip/d":              "",
+		}},
+		{"NO_ARCHIVES", args{false, nil}, map[string]string{
+			"data/a":                   "91a7667cd2256abd",
+			"data/snapshot.tar":        "",
+			"data/old/snapshot.tar.gz": "",
+			"data/snapshot.zip":        "",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "data")
+			writeArchiveTree(t, root)
+			xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+
+			opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, keepOrder: true, archives: tt.args.archives, excludes: tt.args.excludes}
+			appender, err := manifest.NewAppender(xxhsumFilepath, opts.style(), opts.zero)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := searchDir(context.Background(), manifest.DirRoots(root), nil, manifest.New(), nil, appender, nil, opts); got.appended != len(tt.want) {
+				t.Errorf("searchDir() = %v, want %v", got.appended, len(tt.want))
+			}
+			if err := appender.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			dict, _, err := manifest.Load(xxhsumFilepath, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for key, hash := range tt.want {
+				if got, ok := dict.Lookup(filepath.FromSlash(key)); !ok || (hash != "" && got.Checksum() != hash) {
+					t.Errorf("searchDir() wrote %v = %v, want %v", key, got.Checksum(), hash)
+				}
+			}
+			if dict.Len() != len(tt.want) {
+				t.Errorf("searchDir() wrote %v, want %v", dict.Paths(), sortedKeys(tt.want))
+			}
+		})
+	}
+}
+
+func Test_checkDict_archives(t *testing.T) {
+	dir := t.TempDir()
+	writeArchiveTree(t, filepath.Join(dir, "data"))
+
+	dict := newDict(map[string]string{
+		"data/a":                           "91a7667cd2256abd",
+		"data/snapshot.tar/inner/path.txt": "91a7667cd2256abd",
+		"data/snapshot.tar/inner/b.tmp":    "0000000000000000",
+		"data/snapshot.tar/gone":           "91a7667cd2256abd",
+		"data/old/snapshot.tar.gz/c":       "ef46db3751d8e999",
+		"data/snapshot.zip/inner/path.txt": "91a7667cd2256abd",
+		"data/missing.zip/e":               "91a7667cd2256abd",
+	})
+	opts := options{xxhsumFilepath: filepath.Join(dir, "data.xxhsum"), archives: true}
+
+	gotOk, gotFailed, gotMissing := checkDict(context.Background(), dict, opts)
+	if gotOk != 4 || gotFailed != 1 || gotMissing != 2 {
+		t.Errorf("checkDict() = %v, %v, %v, want %v, %v, %v", gotOk, gotFailed, gotMissing, 4, 1, 2)
+	}
+
+	got, err := manifest.FindMissing(context.Background(), dict, filepath.Dir(opts.xxhsumFilepath), opts.archives)
+	if err != nil {
+		t.Errorf("FindMissing() error = %v", err)
+	}
+	if want := []string{filepath.Join("data", "missing.zip", "e"), filepath.Join("data", "snapshot.tar", "gone")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindMissing() = %v, want %v", got, want)
+	}
+
+	opts.archives = false
+	gotOk, gotFailed, gotMissing = checkDict(context.Background(), dict, opts)
+	if gotOk != 1 || gotFailed != 5 || gotMissing != 1 {
+		t.Errorf("checkDict() without archives = %v, %v, %v, want %v, %v, %v", gotOk, gotFailed, gotMissing, 1, 5, 1)
+	}
+}
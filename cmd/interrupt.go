@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/utils"
+)
+
+// `interruptedError` is the cause of a run cancelled by a signal.
+type interruptedError struct {
+	signal os.Signal // `signal` is the signal received.
+}
+
+// Outputs the message naming the signal received.
+func (e interruptedError) Error() string {
+	return "cancelled by signal: " + e.signal.String()
+}
+
+// Outputs a context cancelled on the first SIGINT or SIGTERM, and the function releasing it.
+// Once cancelled, signals are handled by default again, so that a second Ctrl-C ends the run at once.
+func notifyInterrupt() (context.Context, context.CancelFunc) {
+
+	var (
+		signals chan os.Signal = make(chan os.Signal, 1) // `signals` receives SIGINT and SIGTERM.
+	)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			cancel(interruptedError{signal: sig})
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, func() { cancel(context.Canceled) }
+}
+
+// Outputs the exit status of a run cancelled through `ctx`: 128 plus the number of the signal, as shells report it.
+// It is 130 for Ctrl-C and 143 for SIGTERM.
+func interruptStatus(ctx context.Context) int {
+
+	var (
+		interrupted interruptedError // `interrupted` names the signal received.
+	)
+
+	if errors.As(context.Cause(ctx), &interrupted) {
+		if sig, ok := interrupted.signal.(syscall.Signal); ok {
+			return 128 + int(sig)
+		}
+	}
+	return 128 + int(syscall.SIGINT)
+}
+
+// `reportInterrupted` reports the signal that cancelled `ctx`, and outputs the exit status of the run.
+func reportInterrupted(ctx context.Context) int {
+	log.Printf(utils.YELLOW+"WARNING"+utils.RESET+" %v\n", context.Cause(ctx))
+	return interruptStatus(ctx)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/manifest"
+)
+
+func Test_interruptStatus(t *testing.T) {
+	tests := []struct {
+		name  string
+		cause error
+		want  int
+	}{
+		{"SIGINT", interruptedError{signal: os.Interrupt}, 130},
+		{"SIGTERM", interruptedError{signal: syscall.SIGTERM}, 143},
+		{"CANCELLED", context.Canceled, 130},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancelCause(context.Background())
+			cancel(tt.cause)
+			if got := interruptStatus(ctx); got != tt.want {
+				t.Errorf("interruptStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_searchDir_interrupted(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "data")
+	writeArchiveTree(t, root)
+	xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(interruptedError{signal: os.Interrupt})
+
+	opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, archives: true}
+	appender, err := manifest.NewAppender(xxhsumFilepath, opts.style(), opts.zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := searchDir(ctx, manifest.DirRoots(root), []string{filepath.Join(root, "a")}, manifest.New(), nil, appender, nil, opts); got.appended != 0 {
+		t.Errorf("searchDir() = %v, want %v", got.appended, 0)
+	}
+	if err := appender.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(xxhsumFilepath); err != nil || len(content) != 0 {
+		t.Errorf("searchDir() wrote %q, %v, want nothing", content, err)
+	}
+
+	dict := newDict(map[string]string{"data/a": "91a7667cd2256abd", "data/snapshot.tar/inner/path.txt": "91a7667cd2256abd", "data/gone": "0"})
+	if gotOk, gotFailed, gotMissing := checkDict(ctx, dict, opts); gotOk+gotFailed+gotMissing != 0 {
+		t.Errorf("checkDict() = %v, %v, %v, want nothing checked", gotOk, gotFailed, gotMissing)
+	}
+	if got, err := manifest.FindMissing(ctx, dict, filepath.Dir(opts.xxhsumFilepath), opts.archives); len(got) != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("FindMissing() = %v, %v, want none and %v", got, err, context.Canceled)
+	}
+}
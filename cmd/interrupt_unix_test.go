@@ -0,0 +1,26 @@
+//go:build unix
+
+package main
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_notifyInterrupt(t *testing.T) {
+	ctx, stop := notifyInterrupt()
+	defer stop()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("notifyInterrupt() context not cancelled by SIGTERM")
+	}
+	if got := interruptStatus(ctx); got != 143 {
+		t.Errorf("interruptStatus() = %v, want %v", got, 143)
+	}
+}
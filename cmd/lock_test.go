@@ -0,0 +1,144 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/utils"
+)
+
+func Test_acquireLock(t *testing.T) {
+	type args struct {
+		first  bool
+		second bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr string
+	}{
+		{"WRITER_WRITER", args{false, false}, fmt.Sprintf("is locked by process %d; another run is using", os.Getpid())},
+		{"WRITER_READER", args{false, true}, "use --wait to wait for it"},
+		{"READER_WRITER", args{true, false}, "is locked by another process"},
+		{"READER_READER", args{true, true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			xxhsumFilepath := filepath.Join(t.TempDir(), "test.xxhsum")
+
+			first, err := acquireLock(context.Background(), xxhsumFilepath, tt.args.first, false)
+			if err != nil {
+				t.Fatalf("acquireLock() error = %v", err)
+			}
+			defer first.Release()
+
+			second, err := acquireLock(context.Background(), xxhsumFilepath, tt.args.second, false)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("acquireLock() error = %v, want none", err)
+				} else {
+					second.Release()
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("acquireLock() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_acquireLock_unavailable(t *testing.T) {
+	// Lock file can't be created in a missing directory.
+	xxhsumFilepath := filepath.Join(t.TempDir(), "missing", "test.xxhsum")
+
+	if lock, err := acquireLock(context.Background(), xxhsumFilepath, true, false); lock != nil || err != nil {
+		t.Errorf("acquireLock() = %v, %v, want no lock and no error for a reader", lock, err)
+	}
+	if _, err := acquireLock(context.Background(), xxhsumFilepath, false, false); err == nil {
+		t.Errorf("acquireLock() error = %v, want error for a writer", err)
+	}
+}
+
+func Test_acquireLock_interrupted(t *testing.T) {
+	xxhsumFilepath := filepath.Join(t.TempDir(), "test.xxhsum")
+
+	first, err := acquireLock(context.Background(), xxhsumFilepath, false, false)
+	if err != nil {
+		t.Fatalf("acquireLock() error = %v", err)
+	}
+	defer first.Release()
+
+	// Waiting readers and writers alike give up once interrupted, rather than going on without the lock.
+	for _, shared := range []bool{false, true} {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		lock, err := acquireLock(ctx, xxhsumFilepath, shared, true)
+		cancel()
+		if lock != nil || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("acquireLock() shared = %v = %v, %v, want no lock and %v", shared, lock, err, context.DeadlineExceeded)
+		}
+	}
+}
+
+func Test_run_releasesLock(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantStatus int
+	}{
+		{"CHECK_FAILED", []string{"--check"}, 1},
+		{"STRICT_APPEND", []string{"--strict", "data"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "data")
+			if err := os.MkdirAll(root, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, "a"), []byte("Lorem ipsum\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+			if err := os.WriteFile(xxhsumFilepath, []byte("0000000000000000  data/a\nmalformed\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// PATH arguments are relative to `dir`.
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chdir(wd)
+
+			args, commandLine := os.Args, flag.CommandLine
+			defer func() { os.Args, flag.CommandLine = args, commandLine }()
+			os.Args = append([]string{"append-xxhsum", "-x", xxhsumFilepath}, tt.args...)
+			flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+
+			if got := run(); got != tt.wantStatus {
+				t.Errorf("run() = %v, want %v", got, tt.wantStatus)
+			}
+			if pid, err := os.ReadFile(utils.LockFilepath(xxhsumFilepath)); err != nil || len(pid) != 0 {
+				t.Errorf("run() left lock file holding %q, %v, want it empty", pid, err)
+			}
+			if lock, err := acquireLock(context.Background(), xxhsumFilepath, false, false); err != nil {
+				t.Errorf("run() kept the lock, error = %v", err)
+			} else {
+				lock.Release()
+			}
+		})
+	}
+}
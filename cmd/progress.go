@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Intervals between status updates on a terminal line, and in log lines when stderr is not a terminal.
+const (
+	progressRedrawInterval time.Duration = 250 * time.Millisecond
+	progressLogInterval    time.Duration = time.Minute
+)
+
+// `progress` counts files and bytes hashed, and shows them on stderr with throughput and, once totals are known, ETA.
+// On a terminal it redraws a single status line. Otherwise, e.g. under cron, it writes a status line every `interval`.
+// It shows the time spent loading the xxhsum file the same way.
+// Methods of a nil `progress` do nothing, so that it is optional.
+type progress struct {
+	w          io.Writer      // `w` is where the status is shown.
+	tty        bool           // `tty` redraws the status line in place.
+	interval   time.Duration  // `interval` separates status updates.
+	files      atomic.Int64   // `files` counts files hashed.
+	bytes      atomic.Int64   // `bytes` counts bytes hashed, and read from files being hashed.
+	totalFiles atomic.Int64   // `totalFiles` counts files to be hashed, if pre-scanned.
+	totalBytes atomic.Int64   // `totalBytes` counts bytes to be hashed, if pre-scanned.
+	loading    atomic.Bool    // `loading` is set while the xxhsum file is being loaded.
+	scanning   atomic.Bool    // `scanning` is set while totals are being pre-scanned.
+	scanned    atomic.Bool    // `scanned` is set once totals are known.
+	started    atomic.Int64   // `started` is the time hashing started at, in nanoseconds.
+	stop       chan struct{}  // `stop` ends status updates.
+	stopped    sync.WaitGroup // `stopped` tracks the goroutine updating the status.
+}
+
+// Creates the `progress` shown on `w`, updated every `interval`, in place if `tty` is given.
+func newProgress(w io.Writer, tty bool, interval time.Duration) *progress {
+	p := &progress{w: w, tty: tty, interval: interval, stop: make(chan struct{})}
+	p.started.Store(time.Now().UnixNano())
+	return p
+}
+
+// Creates the `progress` shown on stderr: redrawn on a terminal line, unless `verbose` logging would interleave with it,
+// or in log lines otherwise.
+func newStderrProgress(verbose bool) *progress {
+	if term.IsTerminal(int(os.Stderr.Fd())) && !verbose {
+		return newProgress(os.Stderr, true, progressRedrawInterval)
+	}
+	return newProgress(os.Stderr, false, progressLogInterval)
+}
+
+// `start` shows the status every `interval` until `finish` or `end` is called.
+func (p *progress) start() {
+	if p == nil {
+		return
+	}
+	p.stopped.Add(1)
+	go func() {
+		defer p.stopped.Done()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case now := <-ticker.C:
+				p.show(now)
+			}
+		}
+	}()
+}
+
+// `finish` stops status updates, and replaces the status with the `final` message and a summary of what was hashed.
+func (p *progress) finish(final string) {
+	if p == nil {
+		return
+	}
+	p.end(fmt.Sprintf("%s; %s", final, p.summary(time.Now())))
+}
+
+// `end` stops status updates, and replaces the status with the `final` message.
+func (p *progress) end(final string) {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	p.stopped.Wait()
+	if p.tty {
+		fmt.Fprint(p.w, "\r\033[K")
+	}
+	fmt.Fprintln(p.w, final)
+}
+
+// `show` writes the status at `now`.
+func (p *progress) show(now time.Time) {
+	if p.tty {
+		fmt.Fprintf(p.w, "\r\033[K%s", p.status(now))
+	} else {
+		fmt.Fprintln(p.w, p.status(now))
+	}
+}
+
+// `beginLoad` marks the xxhsum file as being loaded.
+func (p *progress) beginLoad() {
+	if p == nil {
+		return
+	}
+	p.loading.Store(true)
+}
+
+// `beginScan` marks totals as being pre-scanned.
+func (p *progress) beginScan() {
+	if p == nil {
+		return
+	}
+	p.scanning.Store(true)
+}
+
+// `addTotal` counts a file of `size` bytes to be hashed in the totals.
+func (p *progress) addTotal(size int64) {
+	if p == nil {
+		return
+	}
+	p.totalFiles.Add(1)
+	p.totalBytes.Add(size)
+}
+
+// `endScan` marks totals as known, and restarts the clock, so that throughput counts hashing only.
+func (p *progress) endScan() {
+	if p == nil {
+		return
+	}
+	p.started.Store(time.Now().UnixNano())
+	p.scanned.Store(true)
+	p.scanning.Store(false)
+}
+
+// `addFile` counts a file hashed.
+func (p *progress) addFile() {
+	if p == nil {
+		return
+	}
+	p.files.Add(1)
+}
+
+// `addBytes` counts `n` bytes hashed.
+func (p *progress) addBytes(n int64) {
+	if p == nil {
+		return
+	}
+	p.bytes.Add(n)
+}
+
+// Outputs the status at `now`: files and bytes hashed, throughput, and with totals known, how far along and ETA.
+func (p *progress) status(now time.Time) string {
+
+	files, bytes := p.files.Load(), p.bytes.Load()
+	totalFiles, totalBytes := p.totalFiles.Load(), p.totalBytes.Load()
+
+	if p.loading.Load() {
+		return fmt.Sprintf("Loading existing xxhsum file: %s", formatDuration(now.Sub(time.Unix(0, p.started.Load()))))
+	}
+
+	if p.scanning.Load() {
+		return fmt.Sprintf("Scanning: %d files, %s to hash", totalFiles, formatBytes(totalBytes))
+	}
+
+	rate := p.rate(now)
+	if !p.scanned.Load() {
+		return fmt.Sprintf("Hashing: %d files, %s, %s/s", files, formatBytes(bytes), formatBytes(int64(rate)))
+	}
+
+	// Files may have grown since pre-scanned.
+	remaining, percent := totalBytes-bytes, 100.0
+	if remaining < 0 {
+		remaining = 0
+	}
+	if totalBytes > 0 && bytes < totalBytes {
+		percent = 100 * float64(bytes) / float64(totalBytes)
+	}
+	eta := "unknown"
+	if rate > 0 {
+		eta = formatDuration(time.Duration(float64(remaining) / rate * float64(time.Second)))
+	}
+	return fmt.Sprintf("Hashing: %d/%d files, %s/%s (%.0f%%), %s/s, ETA %s",
+		files, totalFiles, formatBytes(bytes), formatBytes(totalBytes), percent, formatBytes(int64(rate)), eta)
+}
+
+// Outputs the summary at `now` of what was hashed, how long it took and how fast.
+func (p *progress) summary(now time.Time) string {
+	elapsed := now.Sub(time.Unix(0, p.started.Load()))
+	return fmt.Sprintf("%d files, %s hashed in %s, %s/s",
+		p.files.Load(), formatBytes(p.bytes.Load()), formatDuration(elapsed), formatBytes(int64(p.rate(now))))
+}
+
+// Outputs bytes hashed per second since hashing started, until `now`.
+func (p *progress) rate(now time.Time) float64 {
+	elapsed := now.Sub(time.Unix(0, p.started.Load())).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(p.bytes.Load()) / elapsed
+}
+
+// `reader` outputs `r`, counting bytes read from it as they are read.
+func (p *progress) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return meteredReader{r: r, p: p}
+}
+
+// `done` counts a file as hashed if `err` is nil. Otherwise, the `read` bytes counted of it are dropped.
+func (p *progress) done(read int64, err error) {
+	if p == nil {
+		return
+	}
+	if err != nil {
+		p.addBytes(-read)
+		return
+	}
+	p.addFile()
+}
+
+// `meteredReader` counts bytes read from `r` in `p`.
+type meteredReader struct {
+	r io.Reader
+	p *progress
+}
+
+// Reads from the reader, counting bytes read.
+func (mr meteredReader) Read(b []byte) (int, error) {
+	n, err := mr.r.Read(b)
+	mr.p.addBytes(int64(n))
+	return n, err
+}
+
+// Outputs `n` bytes in decimal units, e.g. 1.5 GB.
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, exponent := float64(n)/unit, 0
+	for value >= unit && exponent < 4 {
+		value /= unit
+		exponent++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "kMGTP"[exponent])
+}
+
+// Outputs `d` as hours, minutes and seconds, e.g. 1:02:03.
+func formatDuration(d time.Duration) string {
+	seconds := int64(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
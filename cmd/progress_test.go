@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/manifest"
+)
+
+func Test_formatBytes(t *testing.T) {
+	tests := []struct {
+		name string
+		n    int64
+		want string
+	}{
+		{"ZERO", 0, "0 B"},
+		{"BYTES", 999, "999 B"},
+		{"KILO", 1000, "1.0 kB"},
+		{"MEGA", 85300000, "85.3 MB"},
+		{"GIGA", 3400000000, "3.4 GB"},
+		{"PETA", 2000000000000000, "2.0 PB"},
+		{"EXA", 5000000000000000000, "5000.0 PB"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatBytes(tt.n); got != tt.want {
+				t.Errorf("formatBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_formatDuration(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want string
+	}{
+		{"ZERO", 0, "0:00:00"},
+		{"ROUNDED", 1499 * time.Millisecond, "0:00:01"},
+		{"MINUTES", 26*time.Minute + 5*time.Second, "0:26:05"},
+		{"HOURS", 31*time.Hour + 2*time.Minute + 3*time.Second, "31:02:03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatDuration(tt.d); got != tt.want {
+				t.Errorf("formatDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_progress_status(t *testing.T) {
+	type args struct {
+		loading    bool
+		scanning   bool
+		scanned    bool
+		files      int64
+		bytes      int64
+		totalFiles int64
+		totalBytes int64
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"NOT_SCANNED", args{false, false, false, 12, 1200000000, 0, 0}, "Hashing: 12 files, 1.2 GB, 120.0 MB/s"},
+		{"SCANNING", args{false, true, false, 0, 0, 40, 3400000000}, "Scanning: 40 files, 3.4 GB to hash"},
+		{"SCANNED", args{false, false, true, 12, 1200000000, 40, 3400000000}, "Hashing: 12/40 files, 1.2 GB/3.4 GB (35%), 120.0 MB/s, ETA 0:00:18"},
+		{"SCANNED_NOTHING_HASHED", args{false, false, true, 0, 0, 40, 3400000000}, "Hashing: 0/40 files, 0 B/3.4 GB (0%), 0 B/s, ETA unknown"},
+		{"SCANNED_GROWN", args{false, false, true, 2, 3000, 2, 2000}, "Hashing: 2/2 files, 3.0 kB/2.0 kB (100%), 300 B/s, ETA 0:00:00"},
+		{"SCANNED_EMPTY", args{false, false, true, 0, 0, 0, 0}, "Hashing: 0/0 files, 0 B/0 B (100%), 0 B/s, ETA unknown"},
+		{"LOADING", args{true, false, false, 0, 0, 0, 0}, "Loading existing xxhsum file: 0:00:10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Unix(1700000000, 0)
+			p := newProgress(&bytes.Buffer{}, false, time.Minute)
+			p.started.Store(start.UnixNano())
+			p.loading.Store(tt.args.loading)
+			p.scanning.Store(tt.args.scanning)
+			p.scanned.Store(tt.args.scanned)
+			p.files.Store(tt.args.files)
+			p.bytes.Store(tt.args.bytes)
+			p.totalFiles.Store(tt.args.totalFiles)
+			p.totalBytes.Store(tt.args.totalBytes)
+
+			if got := p.status(start.Add(10 * time.Second)); got != tt.want {
+				t.Errorf("progress.status() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_progress_finish(t *testing.T) {
+	tests := []struct {
+		name       string
+		tty        bool
+		wantPrefix string
+	}{
+		{"TTY", true, "\r\033[K"},
+		{"LOG", false, "Hashing: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := newProgress(&buf, tt.tty, time.Millisecond)
+			p.start()
+			p.addFile()
+			p.addBytes(1000)
+			time.Sleep(20 * time.Millisecond)
+			p.finish("Searching complete")
+
+			got := buf.String()
+			if !strings.HasPrefix(got, tt.wantPrefix) {
+				t.Errorf("progress.finish() wrote %q, want prefix %q", got, tt.wantPrefix)
+			}
+			if !strings.Contains(got, "Searching complete; 1 files, 1.0 kB hashed in ") || !strings.HasSuffix(got, "/s\n") {
+				t.Errorf("progress.finish() wrote %q, want final message and summary", got)
+			}
+		})
+	}
+
+	// A nil progress does nothing.
+	var p *progress
+	p.start()
+	p.addFile()
+	p.finish("Searching complete")
+}
+
+func Test_progress_end(t *testing.T) {
+	tests := []struct {
+		name string
+		tty  bool
+		want string
+	}{
+		{"TTY", true, "\r\033[KLoading existing xxhsum file complete\n"},
+		{"LOG", false, "Loading existing xxhsum file complete\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			p := newProgress(&buf, tt.tty, time.Minute)
+			p.beginLoad()
+			p.start()
+			p.end("Loading existing xxhsum file complete")
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("progress.end() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// A nil progress does nothing.
+	var p *progress
+	p.beginLoad()
+	p.end("Loading existing xxhsum file complete")
+}
+
+func Test_progress_done(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantFiles int64
+		wantBytes int64
+	}{
+		{"HASHED", nil, 1, 1000 + 12},
+		{"FAILED", errors.New("read error"), 0, 1000},
+		{"CANCELLED", context.Canceled, 0, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newProgress(&bytes.Buffer{}, false, time.Minute)
+			p.addBytes(1000)
+
+			read, err := io.Copy(io.Discard, p.reader(strings.NewReader("Lorem ipsum\n")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.bytes.Load(); got != 1000+12 {
+				t.Errorf("progress.reader() counted %v bytes while hashing, want %v", got, 1000+12)
+			}
+			p.done(read, tt.err)
+
+			if files, bytes := p.files.Load(), p.bytes.Load(); files != tt.wantFiles || bytes != tt.wantBytes {
+				t.Errorf("progress.done() counted %v files and %v bytes, want %v and %v", files, bytes, tt.wantFiles, tt.wantBytes)
+			}
+		})
+	}
+
+	// A nil progress does nothing.
+	var p *progress
+	if _, err := io.Copy(io.Discard, p.reader(strings.NewReader("Lorem ipsum\n"))); err != nil {
+		t.Fatal(err)
+	}
+	p.done(12, nil)
+}
+
+func Test_searchDir_prescan(t *testing.T) {
+	type args struct {
+		dict   map[string]string
+		update bool
+		dryRun bool
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantFiles int64
+		wantBytes int64
+	}{
+		{"NEW", args{map[string]string{}, false, false}, 6, 12 + 2000 + 12 + 0 + 12 + 1},
+		{"LISTED", args{map[string]string{"data/a": "91a7667cd2256abd", "data/snapshot.zip/d": "0"}, false, false}, 4, 2000 + 12 + 0 + 12},
+		{"UPDATE", args{map[string]string{"data/a": "91a7667cd2256abd", "data/snapshot.zip/d": "0"}, true, false}, 5, 12 + 2000 + 12 + 0 + 12},
+		{"DRY_RUN", args{map[string]string{"data/a": "91a7667cd2256abd"}, true, true}, 1, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			root := filepath.Join(dir, "data")
+			writeArchiveTree(t, root)
+			if err := os.WriteFile(filepath.Join(root, "old", "b"), []byte(strings.Repeat("x", 2000)), 0644); err != nil {
+				t.Fatal(err)
+			}
+			xxhsumFilepath := filepath.Join(dir, "data.xxhsum")
+
+			p := newProgress(&bytes.Buffer{}, false, time.Minute)
+			opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2, archives: true, prescan: true,
+				excludes: []string{"*.tmp"}, update: tt.args.update, dryRun: tt.args.dryRun}
+			appender, err := manifest.NewAppender(xxhsumFilepath, opts.style(), opts.zero)
+			if err != nil {
+				t.Fatal(err)
+			}
+			searchDir(context.Background(), manifest.DirRoots(root), nil, newDict(tt.args.dict), map[string]manifest.FileMeta{}, appender, p, opts)
+			if err := appender.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := p.totalFiles.Load(); got != tt.wantFiles {
+				t.Errorf("searchDir() pre-scanned %v files, want %v", got, tt.wantFiles)
+			}
+			if got := p.totalBytes.Load(); got != tt.wantBytes {
+				t.Errorf("searchDir() pre-scanned %v bytes, want %v", got, tt.wantBytes)
+			}
+			if files, bytes := p.files.Load(), p.bytes.Load(); files != tt.wantFiles || bytes != tt.wantBytes {
+				t.Errorf("searchDir() hashed %v files and %v bytes, want %v and %v", files, bytes, tt.wantFiles, tt.wantBytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package main
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/manifest"
+)
+
+func Test_searchDir_nonRegular(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Mkfifo(filepath.Join(root, "pipe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	// Socket paths are limited in length, so the socket goes to a short relative path.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	listener, err := net.Listen("unix", "sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	xxhsumFilepath := filepath.Join(t.TempDir(), "root.xxhsum")
+	opts := options{xxhsumFilepath: xxhsumFilepath, algorithm: manifest.XXH64, jobs: 2}
+	appender, err := manifest.NewAppender(xxhsumFilepath, opts.style(), opts.zero)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer appender.Close()
+
+	done := make(chan searchResult)
+	go func() {
+		done <- searchDir(context.Background(), manifest.DirRoots(root), nil, manifest.New(), nil, appender, nil, opts)
+	}()
+
+	select {
+	case got := <-done:
+		if got.appended != 1 {
+			t.Errorf("searchDir() appended = %v, want %v", got.appended, 1)
+		}
+		want := map[string]int{"named pipe": 1, "socket": 1, "symbolic link": 1}
+		if !reflect.DeepEqual(got.skipped, want) {
+			t.Errorf("searchDir() skipped = %v, want %v", got.skipped, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("searchDir() blocked on a non-regular file")
+	}
+}
@@ -14,6 +14,9 @@ else
   exit
 fi
 
+# Test first, with the race detector, as files are hashed concurrently
+go test -race ./...
+
 # List architectures to build
 goarchs=('amd64' 'arm64')
 
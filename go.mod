@@ -3,14 +3,11 @@ module github.com/lukasz-lobocki/append-xxhsum
 go 1.20
 
 require (
-	github.com/briandowns/spinner v1.23.1
+	github.com/OneOfOne/xxhash v1.2.8
 	github.com/cespare/xxhash/v2 v2.3.0
+	github.com/zeebo/xxh3 v1.0.2
+	golang.org/x/sys v0.22.0
+	golang.org/x/term v0.22.0
 )
 
-require (
-	github.com/fatih/color v1.17.0 // indirect
-	github.com/mattn/go-colorable v0.1.13 // indirect
-	github.com/mattn/go-isatty v0.0.20 // indirect
-	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/term v0.22.0 // indirect
-)
+require github.com/klauspost/cpuid/v2 v2.0.9 // indirect
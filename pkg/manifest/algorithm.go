@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Describes a hash algorithm of the xxHash family, as named by xxhsum.
+type Algorithm struct {
+	Name      string // `Name` is the value of the --algorithm parameter.
+	Tag       string // `Tag` names the algorithm in BSD-style lines.
+	Width     int    // `Width` is the number of hex digits of a hash.
+	GNUPrefix string // `GNUPrefix` precedes the hash in GNU-style lines, telling apart hashes of equal width.
+}
+
+// Supported algorithms, matching xxhsum -H0, -H1, -H3 and -H2 respectively.
+var (
+	XXH32  = Algorithm{Name: "xxh32", Tag: "XXH32", Width: 8}
+	XXH64  = Algorithm{Name: "xxh64", Tag: "XXH64", Width: 16}
+	XXH3   = Algorithm{Name: "xxh3", Tag: "XXH3", Width: 16, GNUPrefix: "XXH3_"}
+	XXH128 = Algorithm{Name: "xxh128", Tag: "XXH128", Width: 32}
+
+	Algorithms = []Algorithm{XXH32, XXH64, XXH3, XXH128}
+)
+
+// Parses --algorithm parameter. Accepts names and BSD tags in any case, and xxh3-64 for xxh3.
+func ParseAlgorithm(name string) (Algorithm, error) {
+
+	if strings.EqualFold(name, "xxh3-64") {
+		return XXH3, nil
+	}
+	for _, algorithm := range Algorithms {
+		if strings.EqualFold(name, algorithm.Name) {
+			return algorithm, nil
+		}
+	}
+	return Algorithm{}, fmt.Errorf("unknown algorithm: %s; use xxh32, xxh64, xxh3 or xxh128", name)
+}
+
+// `algorithmByTag` outputs the algorithm for the BSD-style `tag`.
+func algorithmByTag(tag string) (Algorithm, bool) {
+
+	for _, algorithm := range Algorithms {
+		if tag == algorithm.Tag {
+			return algorithm, true
+		}
+	}
+	return Algorithm{}, false
+}
+
+// `detectAlgorithm` infers the algorithm of GNU-style `checksum` from its prefix and width. Outputs the bare hash too.
+func detectAlgorithm(checksum string) (Algorithm, string, error) {
+
+	for _, algorithm := range Algorithms {
+		if algorithm.GNUPrefix != "" && strings.HasPrefix(checksum, algorithm.GNUPrefix) {
+			if hash := strings.TrimPrefix(checksum, algorithm.GNUPrefix); isHex(hash, algorithm.Width) {
+				return algorithm, hash, nil
+			}
+			return Algorithm{}, "", fmt.Errorf("malformed %s hash: %s", algorithm.Tag, checksum)
+		}
+	}
+	for _, algorithm := range Algorithms {
+		if algorithm.GNUPrefix == "" && isHex(checksum, algorithm.Width) {
+			return algorithm, checksum, nil
+		}
+	}
+	return Algorithm{}, "", fmt.Errorf("unrecognised hash: %s", checksum)
+}
+
+// Outputs if `s` consists of exactly `width` hex digits.
+func isHex(s string, width int) bool {
+
+	if len(s) != width {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,107 @@
+package manifest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAlgorithm(t *testing.T) {
+	type args struct {
+		name string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Algorithm
+		wantErr bool
+	}{
+		{"XXH32", args{"xxh32"}, XXH32, false},
+		{"XXH64", args{"xxh64"}, XXH64, false},
+		{"XXH3", args{"xxh3"}, XXH3, false},
+		{"XXH3-64", args{"xxh3-64"}, XXH3, false},
+		{"XXH128", args{"xxh128"}, XXH128, false},
+		{"UPPERCASE", args{"XXH128"}, XXH128, false},
+		{"UNKNOWN", args{"md5"}, Algorithm{}, true},
+		{"EMPTY", args{""}, Algorithm{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAlgorithm(tt.args.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAlgorithm() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAlgorithm() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_algorithmByTag(t *testing.T) {
+	type args struct {
+		tag string
+	}
+	tests := []struct {
+		name  string
+		args  args
+		want  Algorithm
+		want1 bool
+	}{
+		{"XXH32", args{"XXH32"}, XXH32, true},
+		{"XXH64", args{"XXH64"}, XXH64, true},
+		{"XXH3", args{"XXH3"}, XXH3, true},
+		{"XXH128", args{"XXH128"}, XXH128, true},
+		{"LOWERCASE", args{"xxh64"}, Algorithm{}, false},
+		{"UNKNOWN", args{"SHA256"}, Algorithm{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1 := algorithmByTag(tt.args.tag)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("algorithmByTag() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("algorithmByTag() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
+
+func Test_detectAlgorithm(t *testing.T) {
+	type args struct {
+		checksum string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    Algorithm
+		want1   string
+		wantErr bool
+	}{
+		{"XXH32", args{"02cc5d05"}, XXH32, "02cc5d05", false},
+		{"XXH64", args{"ef46db3751d8e999"}, XXH64, "ef46db3751d8e999", false},
+		{"XXH64_UPPERCASE", args{"EF46DB3751D8E999"}, XXH64, "EF46DB3751D8E999", false},
+		{"XXH3", args{"XXH3_2d06800538d394c2"}, XXH3, "2d06800538d394c2", false},
+		{"XXH128", args{"99aa06d3014798d86001c324468d497f"}, XXH128, "99aa06d3014798d86001c324468d497f", false},
+		{"XXH3_WRONG_WIDTH", args{"XXH3_2d06"}, Algorithm{}, "", true},
+		{"WRONG_WIDTH", args{"2d06800538"}, Algorithm{}, "", true},
+		{"NOT_HEX", args{"zzzzzzzz"}, Algorithm{}, "", true},
+		{"EMPTY", args{""}, Algorithm{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, got1, err := detectAlgorithm(tt.args.checksum)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("detectAlgorithm() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("detectAlgorithm() got = %v, want %v", got, tt.want)
+			}
+			if got1 != tt.want1 {
+				t.Errorf("detectAlgorithm() got1 = %v, want %v", got1, tt.want1)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package manifest
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Default thresholds at which buffered lines are written to the manifest.
+const (
+	flushSize     int           = 64 * 1024
+	flushInterval time.Duration = time.Second
+)
+
+// Appends entries to a manifest through one long-lived file handle, as lines of one style.
+//
+// Lines are buffered and written once `flushSize` bytes are pending or `flushInterval` has elapsed.
+// Only complete lines are ever written, so an interrupted run leaves no partial line behind.
+// If the last line of the manifest lacks its terminator, e.g. after an interrupted append, it is cut off before entries
+// are written, as `Load` does not load it. Such a file is written to again as it was, if there are no entries.
+type Appender struct {
+	mu        sync.Mutex    // `mu` guards `buffer` and `file` against the background flusher.
+	file      *os.File      // `file` is the manifest opened in append mode.
+	style     Style         // `style` is the style of the lines appended.
+	zero      bool          // `zero` terminates lines by NUL, leaving file names unescaped.
+	buffer    bytes.Buffer  // `buffer` holds complete lines not yet written to `file`.
+	flushSize int           // `flushSize` is the number of pending bytes triggering a write.
+	cut       int64         // `cut` is the size the file is truncated to before the first write, -1 to leave it be.
+	stop      chan struct{} // `stop` ends the background flusher.
+	stopped   chan struct{} // `stopped` is closed once the background flusher has ended.
+	err       error         // `err` keeps the first error of the background flusher.
+}
+
+// Opens the manifest at `outputFile` for appending `style` lines, terminated by NUL if `zero`, creating it if it doesn't exist.
+func NewAppender(outputFile string, style Style, zero bool) (*Appender, error) {
+	return newAppender(outputFile, style, zero, flushSize, flushInterval)
+}
+
+// `newAppender` opens the manifest as `NewAppender` does, with buffered lines written at the given thresholds.
+func newAppender(outputFile string, style Style, zero bool, flushSize int, flushInterval time.Duration) (*Appender, error) {
+
+	file, err := os.OpenFile(outputFile, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %s; %w", outputFile, err)
+	}
+
+	a := &Appender{
+		file:      file,
+		style:     style,
+		zero:      zero,
+		flushSize: flushSize,
+		cut:       -1,
+		stop:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error accessing file: %s; %w", outputFile, err)
+	}
+	if size, err := terminatedSize(file, zero); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error reading file: %s; %w", outputFile, err)
+	} else if size < fileInfo.Size() {
+		// Cut off the incomplete line, so that no entry is glued to it, nor is it completed by a terminator.
+		a.cut = size
+	}
+
+	// Flush lines periodically, so they are kept even if no further line arrives.
+	go func() {
+		defer close(a.stopped)
+
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				a.mu.Lock()
+				if err := a.flush(); err != nil && a.err == nil {
+					a.err = err
+				}
+				a.mu.Unlock()
+			case <-a.stop:
+				return
+			}
+		}
+	}()
+
+	return a, nil
+}
+
+// Buffers the line of the entry `e`, see `Entry.Line`, writing the buffer once it reaches the size threshold.
+func (a *Appender) Append(e Entry) error {
+	return a.writeLine(e.Line(a.style, a.zero))
+}
+
+// Buffers the heading comment of a new manifest of `algorithm` hashes, see `Writer.WriteHeader`.
+func (a *Appender) WriteHeader(algorithm Algorithm) error {
+
+	var (
+		header bytes.Buffer = bytes.Buffer{}
+	)
+
+	if err := NewWriter(&header, a.style, a.zero).WriteHeader(algorithm); err != nil || header.Len() == 0 {
+		return err
+	}
+	return a.writeLine(header.String())
+}
+
+// Writes all buffered lines to the file.
+func (a *Appender) Flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.flush()
+}
+
+// Writes buffered lines, fsyncs and closes the file.
+func (a *Appender) Close() error {
+	close(a.stop)
+	<-a.stopped
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	err := a.flush()
+	if err == nil {
+		err = a.err
+	}
+	if err == nil {
+		err = a.file.Sync()
+	}
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// `writeLine` buffers complete lines, writing the buffer once it reaches the size threshold.
+func (a *Appender) writeLine(line string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.err != nil {
+		return a.err
+	}
+
+	a.buffer.WriteString(line)
+	if a.buffer.Len() >= a.flushSize {
+		return a.flush()
+	}
+	return nil
+}
+
+// `flush` writes buffered lines. Expects `mu` to be held.
+func (a *Appender) flush() error {
+	if a.buffer.Len() == 0 {
+		return nil
+	}
+	if a.cut >= 0 {
+		if err := a.file.Truncate(a.cut); err != nil {
+			return fmt.Errorf("error truncating file: %s; %w", a.file.Name(), err)
+		}
+		a.cut = -1
+	}
+	if _, err := a.file.Write(a.buffer.Bytes()); err != nil {
+		return fmt.Errorf("error appending to file: %s; %w", a.file.Name(), err)
+	}
+	a.buffer.Reset()
+	return nil
+}
+
+// Appends `entries` as `style` lines to the manifest at `outputFile`, creating it if it doesn't exist.
+// If its last line lacks its terminator, e.g. after an interrupted append, it is cut off, see `Appender`.
+// The file is fsynced before it is closed.
+func Append(outputFile string, style Style, zero bool, entries ...Entry) error {
+
+	appender, err := NewAppender(outputFile, style, zero)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := appender.Append(entry); err != nil {
+			appender.Close()
+			return err
+		}
+	}
+	return appender.Close()
+}
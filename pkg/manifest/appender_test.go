@@ -0,0 +1,136 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppender(t *testing.T) {
+	type args struct {
+		existing  string
+		style     Style
+		zero      bool
+		entries   []Entry
+		flushSize int
+	}
+	var (
+		a = Entry{"a", "0000000000000001", XXH64}
+		b = Entry{"b", "0000000000000002", XXH64}
+	)
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"NEW_FILE", args{"", GNU, false, []Entry{a, b}, flushSize}, "0000000000000001 *a\n0000000000000002 *b\n"},
+		{"EXISTING_FILE", args{"x\n", GNU, false, []Entry{a, b}, flushSize}, "x\n0000000000000001 *a\n0000000000000002 *b\n"},
+		{"SIZE_THRESHOLD", args{"", GNU, false, []Entry{a, b}, 1}, "0000000000000001 *a\n0000000000000002 *b\n"},
+		{"NO_ENTRIES", args{"x\n", GNU, false, nil, flushSize}, "x\n"},
+		{"BSD", args{"", BSD, false, []Entry{a}, flushSize}, "XXH64 (a) = 0000000000000001\n"},
+		{"UNTERMINATED", args{"x\n0000000000000002 *b/c", GNU, false, []Entry{a}, flushSize}, "x\n0000000000000001 *a\n"},
+		{"UNTERMINATED_LINE_ONLY", args{"x", GNU, false, []Entry{a}, flushSize}, "0000000000000001 *a\n"},
+		{"UNTERMINATED_NO_ENTRIES", args{"x", GNU, false, nil, flushSize}, "x"},
+		{"ZERO_UNTERMINATED", args{"x\x00y\nz", GNU, true, []Entry{a}, flushSize}, "x\x000000000000000001 *a\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "test.xxhsum")
+			if tt.args.existing != "" {
+				if err := os.WriteFile(filename, []byte(tt.args.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			a, err := newAppender(filename, tt.args.style, tt.args.zero, tt.args.flushSize, flushInterval)
+			if err != nil {
+				t.Fatalf("newAppender() error = %v", err)
+			}
+			for _, entry := range tt.args.entries {
+				if err := a.Append(entry); err != nil {
+					t.Errorf("Appender.Append() error = %v", err)
+				}
+			}
+			if err := a.Close(); err != nil {
+				t.Errorf("Appender.Close() error = %v", err)
+			}
+
+			if got, err := os.ReadFile(filename); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Errorf("Appender wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppender_flushThresholds(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.xxhsum")
+
+	a, err := newAppender(filename, GNU, false, 21, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("newAppender() error = %v", err)
+	}
+	defer a.Close()
+
+	// Below the size threshold, the line stays buffered until the interval elapses.
+	a.Append(Entry{"a", "0000000000000001", XXH64})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := os.ReadFile(filename)
+		if string(got) == "0000000000000001 *a\n" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Appender did not flush on interval, file holds %q", got)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Reaching the size threshold writes immediately.
+	a.Append(Entry{"bbbb", "0000000000000002", XXH64})
+	if got, _ := os.ReadFile(filename); string(got) != "0000000000000001 *a\n0000000000000002 *bbbb\n" {
+		t.Errorf("Appender did not flush on size, file holds %q", got)
+	}
+}
+
+func TestAppender_WriteHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		style Style
+		zero  bool
+		want  string
+	}{
+		{"GNU", GNU, false, "# XXH3 hashes https://xxhash.com/\n# To verify use xxhsum --check --quiet FILEPATH\n"},
+		{"BSD", BSD, false, ""},
+		{"ZERO", GNU, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "test.xxhsum")
+			a, err := NewAppender(filename, tt.style, tt.zero)
+			if err != nil {
+				t.Fatalf("NewAppender() error = %v", err)
+			}
+			if err := a.WriteHeader(XXH3); err != nil {
+				t.Errorf("Appender.WriteHeader() error = %v", err)
+			}
+			if err := a.Close(); err != nil {
+				t.Errorf("Appender.Close() error = %v", err)
+			}
+
+			if got, err := os.ReadFile(filename); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Errorf("Appender.WriteHeader() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewAppender(t *testing.T) {
+	if _, err := NewAppender(filepath.Join(t.TempDir(), "missing", "test.xxhsum"), GNU, false); err == nil {
+		t.Errorf("NewAppender() error = %v, wantErr %v", err, true)
+	}
+}
@@ -0,0 +1,159 @@
+package manifest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// `archiveExtensions` are the file name extensions of recognised archives.
+var archiveExtensions = []string{".tar", ".tar.gz", ".tgz", ".zip"}
+
+// `ArchiveVisitor` is called by `WalkArchive` for each member of an archive.
+// `member` is the slash-separated name of the member, and `r` its content, readable only until the call returns.
+type ArchiveVisitor func(member string, fileInfo fs.FileInfo, r io.Reader) error
+
+// Outputs if the file `name` is a tar or zip archive, recognised by its extension: .tar, .tar.gz, .tgz or .zip.
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, extension := range archiveExtensions {
+		if strings.HasSuffix(lower, extension) && len(lower) > len(extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// Outputs the path of the archive the `path` leads through, and the slash-separated name of the member within it.
+// The archive is the first element of `path` recognised by `IsArchive`. Outputs false if there is none, or no member follows it.
+func SplitArchivePath(path string) (string, string, bool) {
+	elements := strings.Split(path, string(filepath.Separator))
+	for n, element := range elements[:len(elements)-1] {
+		if IsArchive(element) {
+			return strings.Join(elements[:n+1], string(filepath.Separator)), strings.Join(elements[n+1:], "/"), true
+		}
+	}
+	return "", "", false
+}
+
+// Calls `fn` for each member of the archive `name` of `fsys`, in archive order, streaming its content.
+// Tar archives may be gzip-compressed. Zip archives must be opened from files that can be read at any offset, as `os.DirFS` ones can.
+// Directories are not visited. Neither are members whose names are absolute or lead outside the archive.
+// Hard links of tar archives are visited as irregular files, as their content is that of a preceding member.
+func WalkArchive(fsys fs.FS, name string, fn ArchiveVisitor) error {
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		return walkZip(file, name, fn)
+	}
+	return walkTar(file, name, fn)
+}
+
+// `walkZip` calls `fn` for each member of the zip archive `name` read from `file`.
+func walkZip(file fs.File, name string, fn ArchiveVisitor) error {
+
+	readerAt, ok := file.(io.ReaderAt)
+	if !ok {
+		return fmt.Errorf("error reading archive: %s; not readable at any offset", name)
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	reader, err := zip.NewReader(readerAt, fileInfo.Size())
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return fmt.Errorf("error reading archive: %s; %w", name, err)
+	}
+
+	for _, f := range reader.File {
+		member, ok := memberName(f.Name)
+		if !ok || f.FileInfo().IsDir() {
+			continue
+		}
+		if err := visitZipMember(f, member, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// `visitZipMember` calls `fn` for the zip member `f`, closing its content after.
+func visitZipMember(f *zip.File, member string, fn ArchiveVisitor) error {
+
+	r, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("error reading member: %s; %w", member, err)
+	}
+	defer r.Close()
+
+	return fn(member, f.FileInfo(), r)
+}
+
+// `walkTar` calls `fn` for each member of the tar archive `name` read from `file`, gunzipping it first if its name says so.
+func walkTar(file fs.File, name string, fn ArchiveVisitor) error {
+
+	var (
+		r io.Reader = file // `r` reads the uncompressed archive.
+	)
+
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("error reading archive: %s; %w", name, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	reader := tar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %s; %w", name, err)
+		}
+
+		member, ok := memberName(header.Name)
+		if !ok || header.Typeflag == tar.TypeDir {
+			continue
+		}
+		fileInfo := header.FileInfo()
+		if header.Typeflag == tar.TypeLink {
+			fileInfo = irregularFileInfo{fileInfo}
+		}
+		if err := fn(member, fileInfo, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// `irregularFileInfo` describes a member that is neither a regular file nor of any other known type.
+type irregularFileInfo struct {
+	fs.FileInfo
+}
+
+// Outputs the mode of the member, marked irregular.
+func (fi irregularFileInfo) Mode() fs.FileMode {
+	return fi.FileInfo.Mode() | fs.ModeIrregular
+}
+
+// Outputs the slash-separated `name` of an archive member in canonical form, or false if it is absolute or leads outside the archive.
+func memberName(name string) (string, bool) {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	return name, fs.ValidPath(name) && name != "."
+}
@@ -0,0 +1,183 @@
+package manifest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// `archiveMember` is a member written to a test archive.
+type archiveMember struct {
+	name     string
+	body     string
+	typeflag byte
+}
+
+// Outputs a tar archive of `members`, gzip-compressed if `compress` is given.
+func tarArchive(t testing.TB, members []archiveMember, compress bool) []byte {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, m := range members {
+		header := &tar.Header{Name: m.name, Mode: 0644, Size: int64(len(m.body)), Typeflag: m.typeflag}
+		switch m.typeflag {
+		case tar.TypeDir:
+			header.Mode, header.Size = 0755, 0
+		case tar.TypeSymlink, tar.TypeLink:
+			header.Linkname, header.Size = m.body, 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Size > 0 {
+			if _, err := tw.Write([]byte(m.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// Outputs a zip archive of `members`. Members named with a trailing slash are directories.
+func zipArchive(t testing.TB, members []archiveMember) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		w, err := zw.Create(m.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(m.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"snapshot.tar", true},
+		{"snapshot.tar.gz", true},
+		{"snapshot.TGZ", true},
+		{"snapshot.zip", true},
+		{"snapshot.gz", false},
+		{"snapshot.tar.xz", false},
+		{".tar", false},
+		{"tar", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsArchive(tt.name); got != tt.want {
+				t.Errorf("IsArchive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		wantArchive string
+		wantMember  string
+		wantOk      bool
+	}{
+		{"MEMBER", filepath.Join("a", "snapshot.tar", "inner", "path.txt"), filepath.Join("a", "snapshot.tar"), "inner/path.txt", true},
+		{"NESTED", filepath.Join("a.zip", "b.tar", "c"), "a.zip", "b.tar/c", true},
+		{"ARCHIVE", filepath.Join("a", "snapshot.tar"), "", "", false},
+		{"PLAIN", filepath.Join("a", "b", "c"), "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotArchive, gotMember, gotOk := SplitArchivePath(tt.path)
+			if gotArchive != tt.wantArchive || gotMember != tt.wantMember || gotOk != tt.wantOk {
+				t.Errorf("SplitArchivePath() = %v, %v, %v, want %v, %v, %v", gotArchive, gotMember, gotOk, tt.wantArchive, tt.wantMember, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestWalkArchive(t *testing.T) {
+	members := []archiveMember{
+		{"./inner/", "", tar.TypeDir},
+		{"./inner/path.txt", "Lorem ipsum\n", tar.TypeReg},
+		{"b", "", tar.TypeReg},
+		{"../evil", "x", tar.TypeReg},
+		{"/etc/passwd", "x", tar.TypeReg},
+		{"link", "b", tar.TypeSymlink},
+		{"hard", "b", tar.TypeLink},
+	}
+	fsys := fstest.MapFS{
+		"snapshot.tar":    {Data: tarArchive(t, members, false)},
+		"snapshot.tar.gz": {Data: tarArchive(t, members, true)},
+		"snapshot.zip":    {Data: zipArchive(t, []archiveMember{{name: "inner/"}, {name: "inner/path.txt", body: "Lorem ipsum\n"}, {name: "b"}, {name: "../evil", body: "x"}})},
+		"broken.tgz":      {Data: []byte("not gzip")},
+		"broken.zip":      {Data: []byte("not zip")},
+	}
+
+	type visited struct {
+		Mode fs.FileMode
+		Body string
+	}
+	wantTar := map[string]visited{
+		"inner/path.txt": {0644, "Lorem ipsum\n"},
+		"b":              {0644, ""},
+		"link":           {fs.ModeSymlink | 0644, ""},
+		"hard":           {fs.ModeIrregular | 0644, ""},
+	}
+	tests := []struct {
+		name    string
+		archive string
+		want    map[string]visited
+		wantErr bool
+	}{
+		{"TAR", "snapshot.tar", wantTar, false},
+		{"TAR_GZ", "snapshot.tar.gz", wantTar, false},
+		{"ZIP", "snapshot.zip", map[string]visited{"inner/path.txt": {0666, "Lorem ipsum\n"}, "b": {0666, ""}}, false},
+		{"BROKEN_TGZ", "broken.tgz", map[string]visited{}, true},
+		{"BROKEN_ZIP", "broken.zip", map[string]visited{}, true},
+		{"DOES_NOT_EXIST", "missing.tar", map[string]visited{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]visited{}
+			err := WalkArchive(fsys, tt.archive, func(member string, fileInfo fs.FileInfo, r io.Reader) error {
+				body, err := io.ReadAll(r)
+				got[member] = visited{fileInfo.Mode(), string(body)}
+				return err
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("WalkArchive() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WalkArchive() visited %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// `writeFileAtomically` replaces `outputFile` with content produced by `write`.
+// The content goes to a temporary file next to `outputFile` first, which is then renamed over it. Renaming is atomic,
+// so `outputFile` holds either its old or its new content, even if interrupted.
+func writeFileAtomically(outputFile string, perm os.FileMode, write func(writer *bufio.Writer) error) error {
+
+	var (
+		temp   *os.File      = nil
+		writer *bufio.Writer = nil
+		err    error         = nil
+	)
+
+	if temp, err = os.CreateTemp(filepath.Dir(outputFile), filepath.Base(outputFile)+".*.tmp"); err != nil {
+		return fmt.Errorf("error creating temporary file for: %s; %w", outputFile, err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	writer = bufio.NewWriter(temp)
+	if err = write(writer); err != nil {
+		return fmt.Errorf("error writing file: %s; %w", temp.Name(), err)
+	}
+
+	if err = writer.Flush(); err != nil {
+		return fmt.Errorf("error writing file: %s; %w", temp.Name(), err)
+	}
+	if err = temp.Chmod(perm); err != nil {
+		return fmt.Errorf("error writing file: %s; %w", temp.Name(), err)
+	}
+	if err = temp.Sync(); err != nil {
+		return fmt.Errorf("error writing file: %s; %w", temp.Name(), err)
+	}
+	if err = temp.Close(); err != nil {
+		return fmt.Errorf("error writing file: %s; %w", temp.Name(), err)
+	}
+
+	if err = os.Rename(temp.Name(), outputFile); err != nil {
+		return fmt.Errorf("error replacing file: %s; %w", outputFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Suffixes of companion files created next to xxhsum_file: index and lock.
+var companionSuffixes = []string{".meta", ".lock"}
+
+// Outputs if the base `name` is that of xxhsum_file with base name `xxhsumName`, or of a companion file
+// created next to it: index, lock, or temporary file of an atomic rewrite.
+func IsCompanionName(xxhsumName string, name string) bool {
+
+	if name == xxhsumName {
+		return true
+	}
+	if !strings.HasPrefix(name, xxhsumName) {
+		return false
+	}
+
+	// Temporary files are named like `xxhsumName.meta.123456.tmp`.
+	rest := strings.TrimPrefix(name, xxhsumName)
+	if strings.HasPrefix(rest, ".") && strings.HasSuffix(rest, ".tmp") {
+		return true
+	}
+	for _, suffix := range companionSuffixes {
+		if rest == suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// `companions` recognises the manifest and the companion files created next to it, however they are reached.
+type companions struct {
+	xxhsumFilepath string        // `xxhsumFilepath` is the manifest.
+	dirInfo        fs.FileInfo   // `dirInfo` identifies the directory of the manifest.
+	files          []fs.FileInfo // `files` identify the manifest and its index, if they exist.
+}
+
+// Creates `companions` of the `xxhsumFilepath` file.
+func newCompanions(xxhsumFilepath string) *companions {
+
+	c := &companions{xxhsumFilepath: xxhsumFilepath}
+
+	if dirInfo, err := os.Stat(filepath.Dir(xxhsumFilepath)); err == nil {
+		c.dirInfo = dirInfo
+	}
+	for _, path := range []string{xxhsumFilepath, IndexFilepath(xxhsumFilepath)} {
+		if fileInfo, err := os.Stat(path); err == nil {
+			c.files = append(c.files, fileInfo)
+		}
+	}
+	return c
+}
+
+// `match` outputs if the file at `path` is the manifest or its companion.
+// Files are compared by device and inode, so that other spellings of the path, symbolic links and bind mounts are recognised.
+func (c *companions) match(path string, fileInfo fs.FileInfo) bool {
+
+	for _, companion := range c.files {
+		if os.SameFile(companion, fileInfo) {
+			return true
+		}
+	}
+
+	if !IsCompanionName(filepath.Base(c.xxhsumFilepath), filepath.Base(path)) {
+		return false
+	}
+	if filepath.Dir(path) == filepath.Dir(c.xxhsumFilepath) {
+		return true
+	}
+	if c.dirInfo != nil {
+		if dirInfo, err := os.Stat(filepath.Dir(path)); err == nil {
+			return os.SameFile(c.dirInfo, dirInfo)
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestIsCompanionName(t *testing.T) {
+	type args struct {
+		xxhsumName string
+		name       string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{"ITSELF", args{"data.xxhsum", "data.xxhsum"}, true},
+		{"INDEX", args{"data.xxhsum", "data.xxhsum.meta"}, true},
+		{"LOCK", args{"data.xxhsum", "data.xxhsum.lock"}, true},
+		{"TEMP", args{"data.xxhsum", "data.xxhsum.1234567.tmp"}, true},
+		{"INDEX_TEMP", args{"data.xxhsum", "data.xxhsum.meta.1234567.tmp"}, true},
+		{"OTHER", args{"data.xxhsum", "photo.jpg"}, false},
+		{"SAME_PREFIX", args{"data.xxhsum", "data.xxhsum2"}, false},
+		{"OTHER_SUFFIX", args{"data.xxhsum", "data.xxhsum.txt"}, false},
+		{"BACKUP", args{"data.xxhsum", "data.xxhsum.bak"}, false},
+		{"BACKUP_TILDE", args{"data.xxhsum", "data.xxhsum~"}, false},
+		{"OTHER_TEMP", args{"data.xxhsum", "other.xxhsum.1234567.tmp"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsCompanionName(tt.args.xxhsumName, tt.args.name); got != tt.want {
+				t.Errorf("IsCompanionName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package manifest
+
+import (
+	"io/fs"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Outputs the file system of the directory tree at `dir`, as `os.DirFS` does, except that files are opened without
+// blocking. So opening a named pipe found in `dir` does not wait for a writer, and `HashFS` refuses it once opened.
+func DirFS(dir string) fs.FS {
+	return dirFS(dir)
+}
+
+// `dirFS` is the directory tree at the path it holds.
+type dirFS string
+
+// Opens the file `name` of the tree, without blocking.
+func (dir dirFS) Open(name string) (fs.File, error) {
+	filePath, err := dir.join(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	file, err := openFile(filePath)
+	if err != nil {
+		// Errors name the file as given, as `os.DirFS` ones do.
+		if pathErr, ok := err.(*fs.PathError); ok {
+			pathErr.Path = name
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// Outputs the description of the file `name` of the tree, following symbolic links.
+func (dir dirFS) Stat(name string) (fs.FileInfo, error) {
+	filePath, err := dir.join(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		if pathErr, ok := err.(*fs.PathError); ok {
+			pathErr.Path = name
+		}
+		return nil, err
+	}
+	return fileInfo, nil
+}
+
+// Outputs the path of the file `name` of the tree, or an error if `name` is not a valid one.
+func (dir dirFS) join(name string) (string, error) {
+	if !fs.ValidPath(name) || (runtime.GOOS == "windows" && strings.ContainsAny(name, `\:`)) {
+		return "", fs.ErrInvalid
+	}
+	if dir == "" {
+		return "", os.ErrInvalid
+	}
+	return string(dir) + "/" + name, nil
+}
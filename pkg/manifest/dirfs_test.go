@@ -0,0 +1,44 @@
+package manifest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirFS(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", filepath.Join("sub", "b")} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("Lorem ipsum\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fstest.TestFS(DirFS(dir), "a", "sub/b"); err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{"missing", fs.ErrNotExist},
+		{"../a", fs.ErrInvalid},
+		{"/a", fs.ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DirFS(dir).Open(tt.name)
+			var pathErr *fs.PathError
+			if !errors.Is(err, tt.wantErr) || !errors.As(err, &pathErr) || pathErr.Path != tt.name {
+				t.Errorf("DirFS().Open() error = %v, want %v naming %v", err, tt.wantErr, tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A file listed in a manifest with its hash.
+type Entry struct {
+	Path      string    // `Path` is the file name relative to the directory of the manifest.
+	Hash      string    // `Hash` is the hash in hex, without the XXH3_ prefix of GNU-style lines.
+	Algorithm Algorithm // `Algorithm` is the algorithm `Hash` was calculated with, zero if unrecognised.
+}
+
+// Outputs the entry of file name `path` with `checksum` as GNU-style lines have it, e.g. XXH3_ prefixed for XXH3.
+// The algorithm is inferred from the prefix and width of `checksum`. If it can't be, the entry keeps `checksum`
+// as its hash, with no algorithm, and an error is output too.
+func NewEntry(path string, checksum string) (Entry, error) {
+	algorithm, hash, err := detectAlgorithm(checksum)
+	if err != nil {
+		return Entry{Path: path, Hash: checksum}, err
+	}
+	return Entry{Path: path, Hash: hash, Algorithm: algorithm}, nil
+}
+
+// Outputs if the algorithm of the entry was recognised, so that it can be verified.
+func (e Entry) Recognised() bool {
+	return e.Algorithm.Width > 0
+}
+
+// Outputs the hash as GNU-style lines have it, e.g. XXH3_ prefixed for XXH3.
+func (e Entry) Checksum() string {
+	return e.Algorithm.GNUPrefix + e.Hash
+}
+
+// Outputs if `hash`, in hex, matches the hash of the entry, in any case.
+func (e Entry) Matches(hash string) bool {
+	return strings.EqualFold(e.Hash, hash)
+}
+
+// Outputs the line of the entry in `style`, including its terminator: NUL with `zero`, newline otherwise.
+// File names with backslashes or line breaks are escaped, and the line is marked with a leading backslash, as coreutils do.
+// With `zero` the file name is written verbatim.
+func (e Entry) Line(style Style, zero bool) string {
+
+	var (
+		mark string = ""               // `mark` is a leading backslash if `path` is escaped.
+		path string = e.Path           // `path` is the file name as written.
+		end  string = terminator(zero) // `end` terminates the line.
+	)
+
+	if escaped, ok := escapeFileName(path); ok && !zero {
+		mark, path = `\`, escaped
+	}
+
+	if style == BSD {
+		return fmt.Sprintf("%s%s (%s) = %s%s", mark, e.Algorithm.Tag, path, e.Hash, end)
+	}
+	return fmt.Sprintf("%s%s%s *%s%s", mark, e.Algorithm.GNUPrefix, e.Hash, path, end)
+}
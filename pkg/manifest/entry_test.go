@@ -0,0 +1,81 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		checksum string
+		want     Entry
+		wantErr  bool
+	}{
+		{"XXH64", "a", "0d3148243051664f", Entry{"a", "0d3148243051664f", XXH64}, false},
+		{"XXH3", "a", "XXH3_2d06800538d394c2", Entry{"a", "2d06800538d394c2", XXH3}, false},
+		{"XXH32", "a", "02cc5d05", Entry{"a", "02cc5d05", XXH32}, false},
+		{"XXH128", "a", "99aa06d3014798d86001c324468d497f", Entry{"a", "99aa06d3014798d86001c324468d497f", XXH128}, false},
+		{"UNRECOGNISED", "a", "123", Entry{Path: "a", Hash: "123"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewEntry(tt.path, tt.checksum)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NewEntry() = %+v, want %+v", got, tt.want)
+			}
+			if got.Recognised() == tt.wantErr {
+				t.Errorf("Entry.Recognised() = %v, want %v", got.Recognised(), !tt.wantErr)
+			}
+			if got.Checksum() != tt.checksum {
+				t.Errorf("Entry.Checksum() = %v, want %v", got.Checksum(), tt.checksum)
+			}
+		})
+	}
+}
+
+func TestEntry_Matches(t *testing.T) {
+	e := Entry{"a", "0d3148243051664f", XXH64}
+	if !e.Matches("0D3148243051664F") {
+		t.Errorf("Entry.Matches() = %v, want %v", false, true)
+	}
+	if e.Matches("0000000000000000") {
+		t.Errorf("Entry.Matches() = %v, want %v", true, false)
+	}
+}
+
+func TestEntry_Line(t *testing.T) {
+	type args struct {
+		style Style
+		zero  bool
+	}
+	tests := []struct {
+		name  string
+		entry Entry
+		args  args
+		want  string
+	}{
+		{"BSD", Entry{"/home/lukasz", "123567890123456", XXH64}, args{BSD, false}, "XXH64 (/home/lukasz) = 123567890123456\n"},
+		{"GNU", Entry{"/home/lukasz", "123567890123456", XXH64}, args{GNU, false}, "123567890123456 */home/lukasz\n"},
+		{"BSD_XXH32", Entry{"a", "02cc5d05", XXH32}, args{BSD, false}, "XXH32 (a) = 02cc5d05\n"},
+		{"GNU_XXH32", Entry{"a", "02cc5d05", XXH32}, args{GNU, false}, "02cc5d05 *a\n"},
+		{"BSD_XXH3", Entry{"a", "2d06800538d394c2", XXH3}, args{BSD, false}, "XXH3 (a) = 2d06800538d394c2\n"},
+		{"GNU_XXH3", Entry{"a", "2d06800538d394c2", XXH3}, args{GNU, false}, "XXH3_2d06800538d394c2 *a\n"},
+		{"BSD_XXH128", Entry{"a", "99aa06d3014798d86001c324468d497f", XXH128}, args{BSD, false}, "XXH128 (a) = 99aa06d3014798d86001c324468d497f\n"},
+		{"GNU_XXH128", Entry{"a", "99aa06d3014798d86001c324468d497f", XXH128}, args{GNU, false}, "99aa06d3014798d86001c324468d497f *a\n"},
+		{"BSD_ESCAPED", Entry{"a\nb\\c", "0000000000000001", XXH64}, args{BSD, false}, "\\XXH64 (a\\nb\\\\c) = 0000000000000001\n"},
+		{"GNU_ESCAPED", Entry{"a\rb", "0000000000000001", XXH3}, args{GNU, false}, "\\XXH3_0000000000000001 *a\\rb\n"},
+		{"BSD_ZERO", Entry{"a\nb\\c", "0000000000000001", XXH64}, args{BSD, true}, "XXH64 (a\nb\\c) = 0000000000000001\x00"},
+		{"GNU_ZERO", Entry{"a\rb", "0000000000000001", XXH3}, args{GNU, true}, "XXH3_0000000000000001 *a\rb\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.entry.Line(tt.args.style, tt.args.zero); got != tt.want {
+				t.Errorf("Entry.Line() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
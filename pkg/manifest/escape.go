@@ -0,0 +1,49 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Escapes file names in xxhsum_file lines as GNU coreutils and xxhsum do.
+var nameEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+// Outputs `fileName` escaped for xxhsum_file line, and if it needed escaping at all.
+// Lines with escaped file names start with a backslash, so that file names of other lines are taken verbatim.
+func escapeFileName(fileName string) (string, bool) {
+
+	if !strings.ContainsAny(fileName, "\\\n\r") {
+		return fileName, false
+	}
+	return nameEscaper.Replace(fileName), true
+}
+
+// Reverses `escapeFileName`. Outputs an error on a backslash not followed by one of \, n or r.
+func unescapeFileName(escaped string) (string, error) {
+
+	var (
+		builder strings.Builder = strings.Builder{}
+	)
+
+	for i := 0; i < len(escaped); i++ {
+		if escaped[i] != '\\' {
+			builder.WriteByte(escaped[i])
+			continue
+		}
+
+		if i++; i == len(escaped) {
+			return "", fmt.Errorf("unterminated escape in file name: %s", escaped)
+		}
+		switch escaped[i] {
+		case '\\':
+			builder.WriteByte('\\')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		default:
+			return "", fmt.Errorf("unknown escape \\%c in file name: %s", escaped[i], escaped)
+		}
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,58 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func Test_escapeFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		want        string
+		wantEscaped bool
+	}{
+		{"PLAIN", "a/b c.txt", "a/b c.txt", false},
+		{"BACKSLASH", `a\b`, `a\\b`, true},
+		{"NEWLINE", "a\nb", `a\nb`, true},
+		{"CARRIAGE_RETURN", "a\rb", `a\rb`, true},
+		{"ALL", "\\\n\r", `\\\n\r`, true},
+		{"ESCAPE_LOOKALIKE", `a\nb`, `a\\nb`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotEscaped := escapeFileName(tt.fileName)
+			if got != tt.want || gotEscaped != tt.wantEscaped {
+				t.Errorf("escapeFileName() = %q, %v, want %q, %v", got, gotEscaped, tt.want, tt.wantEscaped)
+			}
+			if back, err := unescapeFileName(got); tt.wantEscaped && (err != nil || back != tt.fileName) {
+				t.Errorf("unescapeFileName() = %q, %v, want %q", back, err, tt.fileName)
+			}
+		})
+	}
+}
+
+func Test_unescapeFileName(t *testing.T) {
+	tests := []struct {
+		name    string
+		escaped string
+		want    string
+		wantErr bool
+	}{
+		{"PLAIN", "a/b", "a/b", false},
+		{"ESCAPES", `a\\b\nc\rd`, "a\\b\nc\rd", false},
+		{"UNKNOWN_ESCAPE", `a\tb`, "", true},
+		{"UNTERMINATED", `a\`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := unescapeFileName(tt.escaped)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("unescapeFileName() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("unescapeFileName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
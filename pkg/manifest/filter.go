@@ -0,0 +1,125 @@
+package manifest
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// `filter` decides which paths under `root` are hashed, by exclude, include and .xxhsumignore patterns.
+type filter struct {
+	fsys     fs.FS                // `fsys` holds the walked tree, which .xxhsumignore files are read from.
+	root     string               // `root` is the walked directory, which exclude and include patterns are relative to.
+	excludes *Patterns            // `excludes` are the exclude patterns.
+	includes *Patterns            // `includes` are the include patterns.
+	ignores  map[string]*Patterns // `ignores` holds patterns of .xxhsumignore files by their directory.
+}
+
+// Creates the `filter` for the `root` directory, holding the `fsys` tree.
+func newFilter(fsys fs.FS, root string, excludes []string, includes []string) *filter {
+	return &filter{
+		fsys:     fsys,
+		root:     root,
+		excludes: NewPatterns(excludes),
+		includes: NewPatterns(includes),
+		ignores:  make(map[string]*Patterns),
+	}
+}
+
+// `loadIgnoreFile` loads the .xxhsumignore file of the `dir` directory, if there is one, outputting the error reading it.
+// Directories must be loaded before their content is filtered.
+func (f *filter) loadIgnoreFile(dir string) error {
+	patterns, err := LoadPatternsFS(f.fsys, path.Join(f.relPath(f.root, dir), IgnoreFilename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	f.ignores[dir] = patterns
+	return nil
+}
+
+// `excluded` outputs if the file or directory at `path` matches exclude or .xxhsumignore patterns.
+// Patterns of deeper .xxhsumignore files take precedence.
+func (f *filter) excluded(path string, isDir bool) bool {
+
+	var (
+		excluded bool = false // `excluded` holds the decision of the last matching pattern.
+	)
+
+	if matched, negated := f.excludes.Match(f.relPath(f.root, path), isDir); matched {
+		excluded = !negated
+	}
+
+	for _, dir := range f.ancestors(path) {
+		if patterns, ok := f.ignores[dir]; ok {
+			if matched, negated := patterns.Match(f.relPath(dir, path), isDir); matched {
+				excluded = !negated
+			}
+		}
+	}
+	return excluded
+}
+
+// `excludedWithin` outputs if the file at `path`, or any of its directories below `top`, matches exclude or .xxhsumignore patterns.
+// It filters members of archives, as their directories are not walked.
+func (f *filter) excludedWithin(top string, path string) bool {
+	for dir := filepath.Dir(path); dir != top && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if f.excluded(dir, true) {
+			return true
+		}
+	}
+	return f.excluded(path, false)
+}
+
+// `included` outputs if the file at `path` matches include patterns, itself or by any of its directories.
+// All files are included if there are no include patterns.
+func (f *filter) included(path string) bool {
+
+	var (
+		included bool = false // `included` holds the decision of the last matching pattern.
+	)
+
+	if f.includes.Empty() {
+		return true
+	}
+
+	for _, dir := range f.ancestors(path) {
+		if dir == f.root {
+			continue
+		}
+		if matched, negated := f.includes.Match(f.relPath(f.root, dir), true); matched {
+			included = !negated
+		}
+	}
+	if matched, negated := f.includes.Match(f.relPath(f.root, path), false); matched {
+		included = !negated
+	}
+	return included
+}
+
+// `ancestors` outputs directories from `root` down to the parent of `path`.
+func (f *filter) ancestors(path string) []string {
+
+	var (
+		dirs []string = []string{}
+	)
+
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+		if dir == f.root || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	return dirs
+}
+
+// `relPath` outputs slash-separated `path` relative to the `dir` directory.
+func (f *filter) relPath(dir string, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
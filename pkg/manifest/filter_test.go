@@ -0,0 +1,206 @@
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_filter(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "photos", "raw"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".xxhsumignore"), []byte("*.tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "photos", ".xxhsumignore"), []byte("!keep.tmp\nraw/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		excludes []string
+		includes []string
+		path     string
+		isDir    bool
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantExcluded bool
+		wantIncluded bool
+	}{
+		{"PLAIN", args{nil, nil, "a.txt", false}, false, true},
+		{"EXCLUDE", args{[]string{"*.txt"}, nil, "a.txt", false}, true, true},
+		{"EXCLUDE_DIR", args{[]string{".git/"}, nil, ".git", true}, true, true},
+		{"EXCLUDE_ANCHORED", args{[]string{"/photos/*.jpg"}, nil, "photos/a.jpg", false}, true, true},
+		{"IGNORE_FILE_ROOT", args{nil, nil, "a.tmp", false}, true, true},
+		{"IGNORE_FILE_DEEP", args{nil, nil, "photos/a.tmp", false}, true, true},
+		{"IGNORE_FILE_NEGATED_DEEPER", args{nil, nil, "photos/keep.tmp", false}, false, true},
+		{"IGNORE_FILE_NEGATED_ELSEWHERE", args{nil, nil, "keep.tmp", false}, true, true},
+		{"IGNORE_FILE_RELATIVE", args{nil, nil, "photos/raw", true}, true, true},
+		{"INCLUDE_NO_MATCH", args{nil, []string{"*.jpg"}, "a.txt", false}, false, false},
+		{"INCLUDE_MATCH", args{nil, []string{"*.jpg"}, "photos/a.jpg", false}, false, true},
+		{"INCLUDE_BY_DIR", args{nil, []string{"photos/"}, "photos/raw/a.cr2", false}, false, true},
+		{"INCLUDE_NEGATED", args{nil, []string{"photos/", "!*.cr2"}, "photos/raw/a.cr2", false}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFilter(os.DirFS(root), root, tt.args.excludes, tt.args.includes)
+			for _, dir := range []string{root, filepath.Join(root, "photos"), filepath.Join(root, "photos", "raw")} {
+				if err := f.loadIgnoreFile(dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			path := filepath.Join(root, tt.args.path)
+			if got := f.excluded(path, tt.args.isDir); got != tt.wantExcluded {
+				t.Errorf("filter.excluded() = %v, want %v", got, tt.wantExcluded)
+			}
+			if !tt.args.isDir {
+				if got := f.included(path); got != tt.wantIncluded {
+					t.Errorf("filter.included() = %v, want %v", got, tt.wantIncluded)
+				}
+			}
+		})
+	}
+}
+
+func TestWalk_filter(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "data")
+	for _, name := range []string{"a.jpg", "b.txt", ".git/config", "sub/c.jpg", "sub/node_modules/d.jpg", "sub/e.swp"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "sub", ".xxhsumignore"), []byte("*.swp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		excludes []string
+		includes []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{"ALL", args{nil, nil}, []string{".git/config", "a.jpg", "b.txt", "sub/.xxhsumignore", "sub/c.jpg", "sub/node_modules/d.jpg"}},
+		{"EXCLUDE", args{[]string{".git/", "node_modules/"}, nil}, []string{"a.jpg", "b.txt", "sub/.xxhsumignore", "sub/c.jpg"}},
+		{"INCLUDE", args{nil, []string{"*.jpg"}}, []string{"a.jpg", "sub/c.jpg", "sub/node_modules/d.jpg"}},
+		{"BOTH", args{[]string{"node_modules/"}, []string{"*.jpg"}}, []string{"a.jpg", "sub/c.jpg"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := WalkOptions{Manifest: filepath.Join(dir, "data.xxhsum"), Excludes: tt.args.excludes, Includes: tt.args.includes}
+			got := []string{}
+			if err := Walk(context.Background(), DirRoots(root), nil, opts, func(file File) { got = append(got, file.Path) }); err != nil {
+				t.Fatal(err)
+			}
+			if want := prefixed("data/", tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("Walk() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func Test_companions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "data"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "data"), filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	xxhsumFilepath := filepath.Join(dir, "data", "data.xxhsum")
+	for _, name := range []string{"data.xxhsum", "data.xxhsum.meta", "data.xxhsum.lock", "data.xxhsum.123.tmp", "data.xxhsum.txt", "other.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, "data", name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(xxhsumFilepath, filepath.Join(dir, "hardlink")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"XXHSUM", "data/data.xxhsum", true},
+		{"INDEX", "data/data.xxhsum.meta", true},
+		{"LOCK", "data/data.xxhsum.lock", true},
+		{"TEMP", "data/data.xxhsum.123.tmp", true},
+		{"SIMILAR_NAME", "data/data.xxhsum.txt", false},
+		{"OTHER", "data/other.txt", false},
+		{"SYMLINKED_DIR", "link/data.xxhsum.lock", true},
+		{"DOTTED_PATH", "data/./data.xxhsum", true},
+		{"HARDLINK", "hardlink", true},
+	}
+	c := newCompanions(xxhsumFilepath)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.path)
+			fileInfo, err := os.Lstat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.match(path, fileInfo); got != tt.want {
+				t.Errorf("companions.match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalk_companions(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The manifest and its companions inside the root walked.
+	xxhsumFilepath := filepath.Join(root, "root.xxhsum")
+	for _, name := range []string{"root.xxhsum", "root.xxhsum.meta", "root.xxhsum.lock", "root.xxhsum.meta.42.tmp"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, skipped := []string{}, []string{}
+	opts := WalkOptions{Manifest: xxhsumFilepath, Skipped: func(skip Skip) {
+		if skip.Reason == SkipCompanion {
+			skipped = append(skipped, filepath.Base(skip.Path))
+		}
+	}}
+	listed := []string{filepath.Join(root, "root.xxhsum")}
+	if err := Walk(context.Background(), DirRoots(root), listed, opts, func(file File) { got = append(got, file.Path) }); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.txt", "sub/b.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() = %v, want %v", got, want)
+	}
+	if want := []string{"root.xxhsum", "root.xxhsum.lock", "root.xxhsum.meta", "root.xxhsum.meta.42.tmp"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("Walk() skipped %v, want %v", skipped, want)
+	}
+}
+
+// `prefixed` outputs `paths` prefixed by `prefix`.
+func prefixed(prefix string, paths []string) []string {
+	out := []string{}
+	for _, path := range paths {
+		out = append(out, prefix+path)
+	}
+	return out
+}
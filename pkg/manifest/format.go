@@ -0,0 +1,260 @@
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Style of manifest lines.
+type Style string
+
+const (
+	GNU   Style = "GNU"   // GNU-style lines, as `xxhsum` writes by default.
+	BSD   Style = "BSD"   // BSD-style lines, as `xxhsum --tag` writes.
+	Mixed Style = "mixed" // Both GNU-style and BSD-style lines, as `Report.Style` tells of manifests holding both.
+)
+
+// Summarises lines found while loading a manifest.
+type Report struct {
+	GNULines     int  // `GNULines` counts GNU-style lines.
+	BSDLines     int  // `BSDLines` counts BSD-style lines.
+	Malformed    int  // `Malformed` counts lines that are neither entries with a recognised hash, nor comments, nor blank.
+	Unterminated bool // `Unterminated` is set if the last line lacks its terminator, e.g. after an interrupted append. Such a line is not loaded, nor counted.
+	Mismatched   bool // `Mismatched` is set if lines are terminated the other way: by NUL when loaded as newline-terminated, or by newline only when loaded as NUL-terminated.
+	Conflicting  int  // `Conflicting` counts entries repeating a file name listed before with another hash.
+	Duplicate    int  // `Duplicate` counts entries repeating a file name listed before with the same hash.
+
+	Diagnostics []Diagnostic // `Diagnostics` lists malformed, conflicting and duplicate lines in file order.
+}
+
+// Kind of a problem found in a line of a manifest.
+type DiagnosticKind string
+
+const (
+	DiagnosticMalformed   DiagnosticKind = "malformed"   // Neither an entry with a recognised hash, nor a comment, nor blank.
+	DiagnosticConflicting DiagnosticKind = "conflicting" // File name listed before with another hash, which this line overrides.
+	DiagnosticDuplicate   DiagnosticKind = "duplicate"   // File name listed before with the same hash.
+)
+
+// Problem found in a line of a manifest while loading it.
+type Diagnostic struct {
+	Line     int            // `Line` is the 1-based number of the line.
+	Kind     DiagnosticKind // `Kind` is the problem found.
+	FileName string         // `FileName` is the file name in canonical form, empty for malformed lines.
+	Previous int            // `Previous` is the number of the line listing `FileName` before, 0 for malformed lines.
+}
+
+// Outputs the diagnostic as a message naming its line, e.g. `line 7: "a/b" duplicates line 3`.
+func (d Diagnostic) String() string {
+	switch d.Kind {
+	case DiagnosticConflicting:
+		return fmt.Sprintf("line %d: %q conflicts with line %d, hashes differ", d.Line, d.FileName, d.Previous)
+	case DiagnosticDuplicate:
+		return fmt.Sprintf("line %d: %q duplicates line %d", d.Line, d.FileName, d.Previous)
+	default:
+		return fmt.Sprintf("line %d: malformed", d.Line)
+	}
+}
+
+// Outputs if any malformed, conflicting or duplicate line was found.
+func (r Report) HasProblems() bool {
+	return len(r.Diagnostics) > 0
+}
+
+// Outputs the style of the loaded lines: `GNU`, `BSD`, `Mixed` if there are lines of both, or empty if there are none.
+func (r Report) Style() Style {
+	switch true {
+	case r.GNULines > 0 && r.BSDLines > 0:
+		return Mixed
+	case r.GNULines > 0:
+		return GNU
+	case r.BSDLines > 0:
+		return BSD
+	default:
+		return ""
+	}
+}
+
+// Outputs the canonical form of file name `fileName`, under which it is looked up.
+// Leading ./ and trailing separators are removed, duplicate separators are collapsed and ../ segments are resolved lexically,
+// so that ./a/b, a//b, a/b/ and a/c/../b are all a/b.
+func CanonicalPath(fileName string) string {
+	return filepath.Clean(fileName)
+}
+
+// `isMalformed` outputs if `line`, parsed to `hashValue` and `style`, is malformed: neither an entry with a recognised hash,
+// nor a comment, nor blank. A BSD-style line with a hash not of the width of its tag's algorithm is malformed, see `matchLine`.
+func isMalformed(line string, hashValue string, style Style) bool {
+
+	if style == "" {
+		return strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "#")
+	}
+	_, _, err := detectAlgorithm(hashValue)
+	return err != nil
+}
+
+// `terminatedSize` outputs the size of `file` up to and including the terminator of its last line, which is less than
+// its size if the last line lacks its terminator.
+func terminatedSize(file *os.File, zero bool) (int64, error) {
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	chunk := make([]byte, 4096)
+	for end := fileInfo.Size(); end > 0; {
+		start := end - int64(len(chunk))
+		if start < 0 {
+			start = 0
+		}
+		n, err := file.ReadAt(chunk[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk[:n], terminator(zero)[0]); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
+
+// `terminator` outputs the terminator of manifest lines: NUL with `zero`, newline otherwise.
+func terminator(zero bool) string {
+	if zero {
+		return "\x00"
+	}
+	return "\n"
+}
+
+// Outputs the split function reading manifests line by line, e.g. for `bufio.Scanner`: NUL-terminated with `zero`, newline-terminated otherwise.
+func ScanLines(zero bool) bufio.SplitFunc {
+
+	if !zero {
+		return bufio.ScanLines
+	}
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, 0); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			// Final line without terminator.
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// `scanTerminated` outputs the split function of `ScanLines`, but for a final line lacking its terminator, e.g. after an
+// interrupted append. That line is not output, but kept in `tail`.
+func scanTerminated(zero bool, tail *string) bufio.SplitFunc {
+
+	split := ScanLines(zero)
+
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) > 0 && !bytes.Contains(data, []byte(terminator(zero))) {
+			*tail = string(data)
+			return len(data), nil, nil
+		}
+		return split(data, atEOF)
+	}
+}
+
+// Regular expressions matching manifest lines. They are compiled once, as loading runs them on every line.
+var (
+	/*
+		(?s) lets . match line breaks, which file names of NUL-terminated lines may contain.
+		^ asserts the start of the line.
+		(XXH32|XXH64|XXH3|XXH128) captures the algorithm name.
+		' ' matches space between groups.
+		\( matches the opening parenthesis.
+		(.*) captures any character (greedy) until the last occurrence of a closing parenthesis.
+			This ensures that the match group captures the text between the opening parenthesis and the last closing parenthesis in the file name.
+			Should work correctly even when the file name contains nested parentheses.
+		\) matches the last closing parenthesis.
+		' ' matches space between groups.
+		= matches the equal sign.
+		' ' matches space between groups.
+		(\w+) captures one or more word characters as the hash value.
+		$ asserts the end of the line.
+	*/
+	bsdLineRegex *regexp.Regexp = regexp.MustCompile(`(?s)^(?P<algorithm>XXH32|XXH64|XXH3|XXH128) \((?P<fileName>.*)\) = (?P<hashValue>\w+)$`) // `bsdLineRegex` matches BSD-style lines.
+
+	/*
+		(?s) lets . match line breaks, which file names of NUL-terminated lines may contain.
+		^ asserts the start of the line.
+		(\w+) captures one or more word characters as the hash value, including XXH3_ prefix.
+		' ' matches single space between the two groups.
+		'[ \*]' matches either single space or single asterisk.
+		(.*) captures any remaining characters greedily in the second group.
+		$ asserts the end of the line.
+	*/
+	gnuLineRegex *regexp.Regexp = regexp.MustCompile(`(?s)^(?P<hashValue>\w+) [ \*](?P<fileName>.*)$`) // `gnuLineRegex` matches GNU-style lines.
+)
+
+// `parseLine` parses manifest `line`. Outputs file name, hash as GNU-style line would have it, and style of the line.
+// Style is empty if the line is not an entry.
+//
+// A line starting with a backslash has its file name escaped, see `escapeFileName`. With `zero` file names are never escaped,
+// and may contain line breaks.
+func parseLine(line string, zero bool) (string, string, Style) {
+
+	if escapedLine, ok := strings.CutPrefix(line, `\`); ok && !zero {
+		if strings.HasPrefix(escapedLine, `\`) {
+			// Only a single backslash marks the line.
+			return "", "", ""
+		}
+		fileName, hashValue, style := parseLine(escapedLine, zero)
+		if style == "" {
+			return "", "", ""
+		}
+		if fileName, err := unescapeFileName(fileName); err != nil {
+			return "", "", ""
+		} else {
+			return fileName, hashValue, style
+		}
+	}
+
+	if fileName, hashValue, ok := matchLine(line, bsdLineRegex); ok {
+		return fileName, hashValue, BSD
+	}
+
+	if fileName, hashValue, ok := matchLine(line, gnuLineRegex); ok {
+		return fileName, hashValue, GNU
+	}
+
+	return "", "", ""
+}
+
+// `matchLine` matches the `line` against the `regex`. Outputs file name and hash as GNU-style line would have it.
+//
+// The hash of a BSD-style line is of the algorithm its tag names. A hash of another width is output after the tag instead,
+// e.g. `XXH32 0000000000000001`, so that it is recognised as no algorithm's, nor taken for that of another width.
+func matchLine(line string, regex *regexp.Regexp) (string, string, bool) {
+
+	matches := regex.FindStringSubmatch(line)
+	if matches == nil {
+		return "", "", false
+	}
+
+	fileName, hashValue := matches[regex.SubexpIndex("fileName")], matches[regex.SubexpIndex("hashValue")]
+	if i := regex.SubexpIndex("algorithm"); i >= 0 {
+		if algorithm, ok := algorithmByTag(matches[i]); ok {
+			if !isHex(hashValue, algorithm.Width) {
+				// Not a hash of the tagged algorithm.
+				return fileName, algorithm.Tag + " " + hashValue, true
+			}
+			// BSD-style line. Output the hash as GNU-style line would have it.
+			return fileName, algorithm.GNUPrefix + hashValue, true
+		}
+	}
+	return fileName, hashValue, true
+}
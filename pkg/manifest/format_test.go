@@ -0,0 +1,142 @@
+package manifest
+
+import (
+	"testing"
+)
+
+func TestCanonicalPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		want     string
+	}{
+		{"PLAIN", "a/b", "a/b"},
+		{"DOT_PREFIX", "./a/b", "a/b"},
+		{"DOUBLE_DOT_PREFIX", "././a/b", "a/b"},
+		{"DUPLICATE_SEPARATORS", "a//b", "a/b"},
+		{"TRAILING_SEPARATOR", "a/b/", "a/b"},
+		{"PARENT_SEGMENT", "a/c/../b", "a/b"},
+		{"LEADING_PARENT", "../a/b", "../a/b"},
+		{"SPACES", "./d (1).txt", "d (1).txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanonicalPath(tt.fileName); got != tt.want {
+				t.Errorf("CanonicalPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseLine(t *testing.T) {
+	tests := []struct {
+		name         string
+		line         string
+		zero         bool
+		wantFileName string
+		wantHash     string
+		wantStyle    Style
+	}{
+		{"GNU", "0000000000000001 *a", false, "a", "0000000000000001", GNU},
+		{"BSD", "XXH64 (a) = 0000000000000001", false, "a", "0000000000000001", BSD},
+		{"GNU_VERBATIM_BACKSLASH", `0000000000000001 *a\nb`, false, `a\nb`, "0000000000000001", GNU},
+		{"GNU_ESCAPED", `\0000000000000001 *a\nb\\c\rd`, false, "a\nb\\c\rd", "0000000000000001", GNU},
+		{"BSD_ESCAPED", `\XXH3 (a\nb) = 0000000000000001`, false, "a\nb", "XXH3_0000000000000001", BSD},
+		{"ESCAPED_UNKNOWN", `\0000000000000001 *a\tb`, false, "", "", ""},
+		{"DOUBLE_MARK", `\\0000000000000001 *a`, false, "", "", ""},
+		{"ZERO_VERBATIM", "0000000000000001 *a\nb\\c", true, "a\nb\\c", "0000000000000001", GNU},
+		{"ZERO_BSD", "XXH64 (a\n) = 0000000000000001", true, "a\n", "0000000000000001", BSD},
+		{"ZERO_NO_ESCAPING", `\0000000000000001 *a`, true, "", "", ""},
+		{"BSD_XXH32", "XXH32 (a) = 00000001", false, "a", "00000001", BSD},
+		{"BSD_XXH128", "XXH128 (a) = 00000000000000000000000000000001", false, "a", "00000000000000000000000000000001", BSD},
+		{"BSD_XXH32_TOO_WIDE", "XXH32 (a) = 0000000000000001", false, "a", "XXH32 0000000000000001", BSD},
+		{"BSD_XXH64_TOO_NARROW", "XXH64 (a) = 00000001", false, "a", "XXH64 00000001", BSD},
+		{"BSD_XXH3_TOO_NARROW", "XXH3 (a) = 00000001", false, "a", "XXH3 00000001", BSD},
+		{"COMMENT", "# a", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotFileName, gotHash, gotStyle := parseLine(tt.line, tt.zero)
+			if gotFileName != tt.wantFileName || gotHash != tt.wantHash || gotStyle != tt.wantStyle {
+				t.Errorf("parseLine() = %q, %q, %v, want %q, %q, %v", gotFileName, gotHash, gotStyle, tt.wantFileName, tt.wantHash, tt.wantStyle)
+			}
+		})
+	}
+}
+
+func Test_isMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want bool
+	}{
+		{"GNU", "0000000000000001 *a", false},
+		{"BSD", "XXH64 (a) = 0000000000000001", false},
+		{"BSD_XXH32", "XXH32 (a) = 00000001", false},
+		{"BSD_XXH32_TOO_WIDE", "XXH32 (a) = 0000000000000001", true},
+		{"BSD_XXH64_TOO_NARROW", "XXH64 (a) = 00000001", true},
+		{"BSD_XXH128_TOO_NARROW", "XXH128 (a) = 0000000000000001", true},
+		{"BSD_NOT_HEX", "XXH64 (a) = 000000000000000g", true},
+		{"COMMENT", "# a", false},
+		{"BLANK", " ", false},
+		{"GARBAGE", "garbage", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, hashValue, style := parseLine(tt.line, false)
+			if got := isMalformed(tt.line, hashValue, style); got != tt.want {
+				t.Errorf("isMalformed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiagnostic_String(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Diagnostic
+		want string
+	}{
+		{"MALFORMED", Diagnostic{Line: 2, Kind: DiagnosticMalformed}, "line 2: malformed"},
+		{"DUPLICATE", Diagnostic{Line: 7, Kind: DiagnosticDuplicate, FileName: "a/b", Previous: 3}, `line 7: "a/b" duplicates line 3`},
+		{"CONFLICTING", Diagnostic{Line: 9, Kind: DiagnosticConflicting, FileName: "c", Previous: 1}, `line 9: "c" conflicts with line 1, hashes differ`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.d.String(); got != tt.want {
+				t.Errorf("Diagnostic.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport_Style(t *testing.T) {
+	tests := []struct {
+		name string
+		r    Report
+		want Style
+	}{
+		{"NONE", Report{}, ""},
+		{"GNU", Report{GNULines: 2}, GNU},
+		{"BSD", Report{BSDLines: 2}, BSD},
+		{"MIXED", Report{GNULines: 1, BSDLines: 1}, Mixed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.Style(); got != tt.want {
+				t.Errorf("Report.Style() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Benchmark_parseLine(b *testing.B) {
+	lines := []string{
+		"0000000000000001 *dir001/sub (1)/file0000001.jpg",
+		"XXH64 (dir001/sub (1)/file0000001.jpg) = 0000000000000001",
+		`\0000000000000001 *dir\\001/file\n0000001.jpg`,
+	}
+	for i := 0; i < b.N; i++ {
+		parseLine(lines[i%len(lines)], false)
+	}
+}
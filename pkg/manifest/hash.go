@@ -0,0 +1,153 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+
+	xxhash32 "github.com/OneOfOne/xxhash"
+	"github.com/cespare/xxhash/v2"
+	"github.com/zeebo/xxh3"
+)
+
+// Outputs the `algorithm` hash of the content read from `r`, in hex, as lines have it without prefix.
+func Hash(r io.Reader, algorithm Algorithm) (string, error) {
+	return HashContext(context.Background(), r, algorithm)
+}
+
+// Outputs the `algorithm` hash of the content read from `r`, as `Hash` does.
+// Reading stops once `ctx` is done, outputting its error.
+func HashContext(ctx context.Context, r io.Reader, algorithm Algorithm) (string, error) {
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	r = contextReader{ctx: ctx, r: r}
+
+	switch algorithm {
+	case XXH32:
+		hash := xxhash32.New32()
+		if _, err := io.Copy(hash, r); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%0*x", algorithm.Width, hash.Sum32()), nil
+	case XXH64:
+		hash := xxhash.New()
+		if _, err := io.Copy(hash, r); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%0*x", algorithm.Width, hash.Sum64()), nil
+	case XXH3:
+		hash := xxh3.New()
+		if _, err := io.Copy(hash, r); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%0*x", algorithm.Width, hash.Sum64()), nil
+	case XXH128:
+		hash := xxh3.New()
+		if _, err := io.Copy(hash, r); err != nil {
+			return "", err
+		}
+		sum := hash.Sum128().Bytes()
+		return fmt.Sprintf("%x", sum[:]), nil
+	}
+	return "", fmt.Errorf("unsupported algorithm: %s", algorithm.Name)
+}
+
+// Outputs the `algorithm` hash of the file at `filePath`. Files other than regular ones are refused, as reading them may block.
+func HashFile(filePath string, algorithm Algorithm) (string, error) {
+	return HashFileContext(context.Background(), filePath, algorithm)
+}
+
+// Outputs the `algorithm` hash of the file at `filePath`, as `HashFile` does. Reading stops once `ctx` is done.
+func HashFileContext(ctx context.Context, filePath string, algorithm Algorithm) (string, error) {
+
+	// Opened before checked, so that the file can't be swapped in between. Opening without blocking lets named pipes in.
+	file, err := openFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if fileInfo, err := file.Stat(); err != nil {
+		return "", err
+	} else if !fileInfo.Mode().IsRegular() {
+		return "", fmt.Errorf("not a regular file: %s", filePath)
+	}
+
+	return HashContext(ctx, file, algorithm)
+}
+
+// Outputs the `algorithm` hash of the file `name` of `fsys`, e.g. a directory from `os.DirFS`, an archive or an embedded FS.
+// Files other than regular ones are refused, as reading them may block. Opening them may block too, unless `fsys` opens
+// files without blocking, as `DirFS` does.
+func HashFS(fsys fs.FS, name string, algorithm Algorithm) (string, error) {
+	return HashFSContext(context.Background(), fsys, name, algorithm)
+}
+
+// Outputs the `algorithm` hash of the file `name` of `fsys`, as `HashFS` does. Reading stops once `ctx` is done.
+func HashFSContext(ctx context.Context, fsys fs.FS, name string, algorithm Algorithm) (string, error) {
+	return hashFS(ctx, fsys, name, algorithm, nil)
+}
+
+// `hashFS` outputs the `algorithm` hash of the file `name` of `fsys`, as `HashFSContext` does, reading the file
+// through `wrap` unless it is nil.
+func hashFS(ctx context.Context, fsys fs.FS, name string, algorithm Algorithm, wrap func(io.Reader) io.Reader) (string, error) {
+
+	// Opened before checked, so that the file can't be swapped in between.
+	file, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if fileInfo, err := file.Stat(); err != nil {
+		return "", err
+	} else if !fileInfo.Mode().IsRegular() {
+		return "", fmt.Errorf("not a regular file: %s", name)
+	}
+
+	var r io.Reader = file
+	if wrap != nil {
+		r = wrap(r)
+	}
+	return HashContext(ctx, r, algorithm)
+}
+
+// `contextReader` reads from `r` until `ctx` is done.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Reads from the underlying reader, or outputs the error of the context once it is done.
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// Outputs a manifest of every regular file of `fsys`, hashed with `algorithm`. File names are relative to the root of `fsys`.
+// Symbolic links, named pipes, sockets and devices are skipped.
+func Build(fsys fs.FS, algorithm Algorithm) (*Manifest, error) {
+
+	m := New()
+	err := fs.WalkDir(fsys, ".", func(name string, di fs.DirEntry, err error) error {
+		if err != nil || !di.Type().IsRegular() {
+			return err
+		}
+		hash, err := HashFS(fsys, name, algorithm)
+		if err != nil {
+			return err
+		}
+		m.Add(Entry{Path: filepath.FromSlash(name), Hash: hash, Algorithm: algorithm})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
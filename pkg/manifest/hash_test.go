@@ -0,0 +1,173 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHashFile(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	type args struct {
+		filePath  string
+		algorithm Algorithm
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{"EXISTS", args{filepath.Join("..", "..", "tst", "test1.xxhsum"), XXH64}, "1b4378db293122d8", false},
+		{"DOES_NOT_EXIST", args{filepath.Join("..", "..", "tst", "tes.xxhm"), XXH64}, "", true},
+		{"EMPTY_XXH32", args{empty, XXH32}, "02cc5d05", false},
+		{"EMPTY_XXH64", args{empty, XXH64}, "ef46db3751d8e999", false},
+		{"EMPTY_XXH3", args{empty, XXH3}, "2d06800538d394c2", false},
+		{"EMPTY_XXH128", args{empty, XXH128}, "99aa06d3014798d86001c324468d497f", false},
+		{"UNSUPPORTED", args{empty, Algorithm{Name: "md5"}}, "", true},
+		{"DIRECTORY", args{filepath.Dir(empty), XXH64}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashFile(tt.args.filePath, tt.args.algorithm)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HashFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("HashFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a":       {Data: []byte("Lorem ipsum\n")},
+		"sub/b":   {Data: nil},
+		"pipe":    {Mode: fs.ModeNamedPipe},
+		"sub/dir": {Mode: fs.ModeDir},
+	}
+
+	type args struct {
+		name      string
+		algorithm Algorithm
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{"EXISTS", args{"a", XXH64}, "91a7667cd2256abd", false},
+		{"NESTED_XXH3", args{"sub/b", XXH3}, "2d06800538d394c2", false},
+		{"DOES_NOT_EXIST", args{"c", XXH64}, "", true},
+		{"NAMED_PIPE", args{"pipe", XXH64}, "", true},
+		{"DIRECTORY", args{"sub/dir", XXH64}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HashFS(fsys, tt.args.name, tt.args.algorithm)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("HashFS() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("HashFS() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a":         {Data: []byte("Lorem ipsum\n")},
+		"sub/b":     {Data: nil},
+		"sub/c/d e": {Data: []byte("Lorem ipsum\n")},
+		"link":      {Data: []byte("a"), Mode: fs.ModeSymlink},
+		"pipe":      {Mode: fs.ModeNamedPipe},
+	}
+
+	got, err := Build(fsys, XXH64)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	want := []Entry{
+		{filepath.Join("a"), "91a7667cd2256abd", XXH64},
+		{filepath.Join("sub", "b"), "ef46db3751d8e999", XXH64},
+		{filepath.Join("sub", "c", "d e"), "91a7667cd2256abd", XXH64},
+	}
+	if !reflect.DeepEqual(got.Entries(), want) {
+		t.Errorf("Build() = %v, want %v", got.Entries(), want)
+	}
+
+	if _, err := Build(fstest.MapFS{}, Algorithm{Name: "md5"}); err != nil {
+		t.Errorf("Build() of an empty tree error = %v", err)
+	}
+	if _, err := Build(fsys, Algorithm{Name: "md5"}); err == nil {
+		t.Errorf("Build() with an unsupported algorithm error = nil, want error")
+	}
+}
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm Algorithm
+		want      string
+	}{
+		{"XXH32", XXH32, "636f7ff3"},
+		{"XXH64", XXH64, "91a7667cd2256abd"},
+		{"XXH3", XXH3, "2eb309641069393f"},
+		{"XXH128", XXH128, "12ef334937757f1a2883795504f802f5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Hash(strings.NewReader("Lorem ipsum\n"), tt.algorithm)
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Hash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// `cancellingReader` cancels its context once read from, as an interrupt arriving mid-file does.
+type cancellingReader struct {
+	cancel context.CancelFunc
+}
+
+// Cancels the context and outputs a full buffer of zeroes.
+func (cr cancellingReader) Read(p []byte) (int, error) {
+	cr.cancel()
+	return len(p), nil
+}
+
+func TestHashContext(t *testing.T) {
+	if got, err := HashContext(context.Background(), strings.NewReader("Lorem ipsum\n"), XXH64); err != nil || got != "91a7667cd2256abd" {
+		t.Errorf("HashContext() = %v, %v, want %v", got, err, "91a7667cd2256abd")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := HashContext(ctx, strings.NewReader("Lorem ipsum\n"), XXH64); !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext() of cancelled context error = %v, want %v", err, context.Canceled)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	if _, err := HashContext(ctx, cancellingReader{cancel: cancel}, XXH3); !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext() cancelled while reading error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,43 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package manifest
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHashFile_namedPipe(t *testing.T) {
+	pipe := filepath.Join(t.TempDir(), "pipe")
+	if err := syscall.Mkfifo(pipe, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := HashFile(pipe, XXH64); err == nil {
+		t.Errorf("HashFile() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestHashFS_namedPipe(t *testing.T) {
+	dir := t.TempDir()
+	if err := syscall.Mkfifo(filepath.Join(dir, "pipe"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := HashFS(DirFS(dir), "pipe", XXH64)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("HashFS() error = %v, wantErr %v", err, true)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("HashFS() blocked on a named pipe")
+	}
+}
@@ -0,0 +1,195 @@
+package manifest
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// A file to be hashed by a `Hasher`.
+type HashJob struct {
+	Path      string    // `Path` is the file name of the new entry, relative to the directory of the manifest.
+	FS        fs.FS     // `FS` holds the file `Name`, unless `Reader` is given.
+	Name      string    // `Name` is the slash-separated name of the file within `FS`.
+	Reader    io.Reader // `Reader` streams the content of the file instead, e.g. of an archive member.
+	Algorithm Algorithm // `Algorithm` hashes the file.
+	Previous  Entry     // `Previous` is the entry listed for the file so far, with empty path if none.
+	Meta      FileMeta  // `Meta` holds size, mtime and inode of the file when queued, for the index.
+}
+
+// A `HashJob` with the outcome of hashing it.
+//
+// Its `Meta` tells the time the file was hashed at too, that is when hashing it started.
+type HashResult struct {
+	HashJob
+	Entry   Entry     // `Entry` is the new entry of the file, if hashed.
+	Started time.Time // `Started` is when hashing the file started.
+	Read    int64     // `Read` counts bytes read from the file, also if hashing it failed.
+	Err     error     // `Err` is the error hashing the file, that of the context if hashing was abandoned.
+}
+
+// Outputs if the file was listed with another hash than it has now. New files and files failing to be hashed are not.
+func (r HashResult) Changed() bool {
+	return r.Err == nil && r.Previous.Path != "" && !r.Previous.Matches(r.Entry.Hash)
+}
+
+// Settings of a `Hasher`.
+type HashOptions struct {
+	Jobs      int                       // `Jobs` is the number of files hashed in parallel, at least 1.
+	KeepOrder bool                      // `KeepOrder` passes results in the order the jobs were given in.
+	Reader    func(io.Reader) io.Reader // `Reader` wraps readers of file content, e.g. to meter progress, unless it is nil.
+}
+
+// Hashes files in parallel, passing the results one at a time to a single function, e.g. appending entries of new files.
+type Hasher struct {
+	ctx     context.Context  // `ctx` abandons hashing once done.
+	opts    HashOptions      // `opts` are the settings.
+	fn      func(HashResult) // `fn` gets the results.
+	seq     int              // `seq` counts the jobs given.
+	queue   chan hashTask    // `queue` feeds the workers.
+	results chan hashTask    // `results` feeds `fn`.
+	workers sync.WaitGroup   // `workers` tracks running workers.
+	done    chan struct{}    // `done` is closed once all results are passed to `fn`.
+}
+
+// `hashTask` is a job, or its result, with its position in the order jobs were given in.
+type hashTask struct {
+	seq    int
+	job    HashJob
+	result HashResult
+}
+
+// Outputs a hasher of `opts.Jobs` workers, passing results to `fn`. Calls of `fn` never overlap.
+// Once `ctx` is done, hashing is abandoned, and the results of the jobs left have the error of `ctx`.
+func NewHasher(ctx context.Context, opts HashOptions, fn func(HashResult)) *Hasher {
+
+	if opts.Jobs < 1 {
+		opts.Jobs = 1
+	}
+	h := &Hasher{
+		ctx:     ctx,
+		opts:    opts,
+		fn:      fn,
+		queue:   make(chan hashTask, opts.Jobs),
+		results: make(chan hashTask, opts.Jobs),
+		done:    make(chan struct{}),
+	}
+
+	// Start the workers.
+	for w := 0; w < opts.Jobs; w++ {
+		h.workers.Add(1)
+		go func() {
+			defer h.workers.Done()
+			for task := range h.queue {
+				task.result = h.hash(task.job)
+				h.results <- task
+			}
+		}()
+	}
+
+	// Pass the results on from a single goroutine.
+	go func() {
+		defer close(h.done)
+		h.collect()
+	}()
+
+	return h
+}
+
+// Hashes the file of `job`. Files of `FS` are queued for the workers, blocking while all of them are busy.
+// Files streamed from `Reader` are hashed at once, before returning, as the reader is valid only until then.
+// Not to be called concurrently, nor after `Close`.
+func (h *Hasher) Hash(job HashJob) {
+
+	task := hashTask{seq: h.seq, job: job}
+	h.seq++
+
+	if job.Reader != nil {
+		task.result = h.hash(job)
+		h.results <- task
+		return
+	}
+	h.queue <- task
+}
+
+// Waits for the files queued to be hashed, and their results to be passed.
+func (h *Hasher) Close() {
+	close(h.queue)
+	h.workers.Wait()
+	close(h.results)
+	<-h.done
+}
+
+// `hash` hashes the file of `job`, counting bytes read.
+func (h *Hasher) hash(job HashJob) HashResult {
+
+	var (
+		result   HashResult = HashResult{HashJob: job, Started: time.Now()}
+		checksum string     = ""
+		err      error      = nil
+	)
+
+	result.Meta.HashedAt = result.Started.UnixNano()
+
+	wrap := func(r io.Reader) io.Reader {
+		r = &countingReader{r: r, n: &result.Read}
+		if h.opts.Reader != nil {
+			r = h.opts.Reader(r)
+		}
+		return r
+	}
+
+	if job.Reader != nil {
+		checksum, err = HashContext(h.ctx, wrap(job.Reader), job.Algorithm)
+	} else {
+		checksum, err = hashFS(h.ctx, job.FS, job.Name, job.Algorithm, wrap)
+	}
+
+	result.Err = err
+	if err == nil {
+		result.Entry = Entry{Path: job.Path, Hash: checksum, Algorithm: job.Algorithm}
+	}
+	return result
+}
+
+// `collect` passes results to `fn`. With `KeepOrder`, results ahead of their predecessors are held until those are passed.
+func (h *Hasher) collect() {
+
+	var (
+		next    int              = 0                      // `next` is the position of the result to be passed next.
+		pending map[int]hashTask = make(map[int]hashTask) // `pending` holds results ahead of `next`.
+	)
+
+	for task := range h.results {
+		if !h.opts.KeepOrder {
+			h.fn(task.result)
+			continue
+		}
+
+		pending[task.seq] = task
+		for {
+			if task, ok := pending[next]; ok {
+				delete(pending, next)
+				h.fn(task.result)
+				next++
+			} else {
+				break
+			}
+		}
+	}
+}
+
+// `countingReader` counts bytes read from `r` in `n`.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+// Reads from the underlying reader, counting bytes read.
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	*cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,103 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHasher(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a":     {Data: []byte("Lorem ipsum\n")},
+		"b":     {Data: nil},
+		"large": {Data: []byte(strings.Repeat("x", 1<<20))},
+	}
+	jobs := []HashJob{
+		{Path: "large", FS: fsys, Name: "large", Algorithm: XXH64, Meta: FileMeta{Size: 0}},
+		{Path: "a", FS: fsys, Name: "a", Algorithm: XXH64, Previous: Entry{"a", "91A7667CD2256ABD", XXH64}, Meta: FileMeta{Size: 1}},
+		{Path: "b", FS: fsys, Name: "b", Algorithm: XXH64, Previous: Entry{"b", "0000000000000000", XXH64}, Meta: FileMeta{Size: 2}},
+		{Path: "missing", FS: fsys, Name: "missing", Algorithm: XXH64, Meta: FileMeta{Size: 3}},
+		{Path: "member", Reader: strings.NewReader("Lorem ipsum\n"), Algorithm: XXH64, Meta: FileMeta{Size: 4}},
+	}
+	type want struct {
+		hash    string
+		read    int64
+		changed bool
+		err     bool
+	}
+	wants := []want{
+		{"", 1 << 20, false, false},
+		{"91a7667cd2256abd", 12, false, false},
+		{"ef46db3751d8e999", 0, true, false},
+		{"", 0, false, true},
+		{"91a7667cd2256abd", 12, false, false},
+	}
+
+	var wrapped atomic.Int64
+	got := []HashResult{}
+	h := NewHasher(context.Background(), HashOptions{Jobs: 3, KeepOrder: true, Reader: func(r io.Reader) io.Reader {
+		wrapped.Add(1)
+		return r
+	}}, func(result HashResult) {
+		got = append(got, result)
+	})
+	for _, job := range jobs {
+		h.Hash(job)
+	}
+	h.Close()
+
+	if len(got) != len(jobs) {
+		t.Fatalf("Hasher passed %d results, want %d", len(got), len(jobs))
+	}
+	for i, result := range got {
+		if result.Meta.Size != int64(i) {
+			t.Errorf("Hasher passed result of job %v at %d, want in the order given", result.Meta.Size, i)
+			continue
+		}
+		if result.Meta.HashedAt != result.Started.UnixNano() {
+			t.Errorf("HashResult of %s hashed at %v, want %v", result.Path, result.Meta.HashedAt, result.Started.UnixNano())
+		}
+		if (result.Err != nil) != wants[i].err {
+			t.Errorf("HashResult of %s error = %v, wantErr %v", result.Path, result.Err, wants[i].err)
+		}
+		if wants[i].hash != "" && !reflect.DeepEqual(result.Entry, Entry{result.Path, wants[i].hash, XXH64}) {
+			t.Errorf("HashResult of %s entry = %+v, want hash %v", result.Path, result.Entry, wants[i].hash)
+		}
+		if result.Read != wants[i].read {
+			t.Errorf("HashResult of %s read = %v, want %v", result.Path, result.Read, wants[i].read)
+		}
+		if result.Changed() != wants[i].changed {
+			t.Errorf("HashResult of %s Changed() = %v, want %v", result.Path, result.Changed(), wants[i].changed)
+		}
+	}
+	if got := wrapped.Load(); got != 4 {
+		t.Errorf("Hasher wrapped %d readers, want one for each file opened", got)
+	}
+}
+
+func TestHasher_cancelled(t *testing.T) {
+	fsys := fstest.MapFS{"a": {Data: []byte("Lorem ipsum\n")}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := 0
+	h := NewHasher(ctx, HashOptions{Jobs: 2}, func(result HashResult) {
+		results++
+		if !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("HashResult error = %v, want %v", result.Err, context.Canceled)
+		}
+	})
+	h.Hash(HashJob{Path: "a", FS: fsys, Name: "a", Algorithm: XXH64})
+	h.Hash(HashJob{Path: "b", Reader: strings.NewReader("Lorem ipsum\n"), Algorithm: XXH64})
+	h.Close()
+
+	if results != 2 {
+		t.Errorf("Hasher passed %d results, want %d", results, 2)
+	}
+}
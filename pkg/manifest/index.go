@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Heading comment of the index.
+const indexHeader string = "# size\tmtime\tinode\thashed\tpath"
+
+// Number of tab-separated fields of each index line.
+const indexFields int = 5
+
+// Size, modification time and inode of a file, as recorded when it was hashed, and the time it was hashed at.
+type FileMeta struct {
+	Size     int64  // `Size` is the file size in bytes.
+	ModTime  int64  // `ModTime` is the modification time in nanoseconds since the Unix epoch.
+	Inode    uint64 // `Inode` is the inode number, 0 if unknown, e.g. on Windows.
+	HashedAt int64  // `HashedAt` is the time the file was hashed at in nanoseconds since the Unix epoch, 0 if unknown.
+}
+
+// Outputs the metadata of the file described by `fileInfo`, without the time it was hashed at.
+func NewFileMeta(fileInfo fs.FileInfo) FileMeta {
+	return FileMeta{Size: fileInfo.Size(), ModTime: fileInfo.ModTime().UnixNano(), Inode: inode(fileInfo)}
+}
+
+// Outputs if the file described by `current` is unchanged since `m` was recorded: of the same size and mtime,
+// and with the same inode, unless either is unknown. A file replaced by another, e.g. by a rename, gets another inode.
+func (m FileMeta) Unchanged(current FileMeta) bool {
+	if m.Size != current.Size || m.ModTime != current.ModTime {
+		return false
+	}
+	return m.Inode == 0 || current.Inode == 0 || m.Inode == current.Inode
+}
+
+// Outputs the filepath of the index kept alongside xxhsum_file.
+func IndexFilepath(xxhsumFilepath string) string {
+	return xxhsumFilepath + ".meta"
+}
+
+// Loads the index to the map. A missing index loads empty.
+//
+// Each line holds size, modification time, inode, time hashed at and relative path, separated by tabs.
+// Paths are escaped, see `escapeFileName`. Malformed lines are ignored.
+func LoadIndex(inputFile string) (map[string]FileMeta, error) {
+
+	var (
+		file    *os.File            = nil
+		scanner *bufio.Scanner      = nil
+		err     error               = nil
+		data    map[string]FileMeta = make(map[string]FileMeta)
+	)
+
+	// Open the text file
+	if file, err = os.Open(inputFile); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return data, nil
+		}
+		return nil, fmt.Errorf("error opening file: %s; %w", inputFile, err)
+	}
+	defer file.Close()
+
+	// Read the file line by line
+	scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if fileName, meta, ok := parseIndexLine(line); ok {
+			data[fileName] = meta
+		}
+	}
+
+	// Check for any scanning errors
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning: %s; %w", inputFile, err)
+	}
+
+	return data, nil
+}
+
+// Parses index `line` of `indexFields` tab-separated fields. Outputs the unescaped path, its metadata and if the line is valid.
+func parseIndexLine(line string) (string, FileMeta, bool) {
+
+	var (
+		meta   FileMeta = FileMeta{}
+		values []string = strings.SplitN(line, "\t", indexFields)
+		err    error    = nil
+	)
+
+	if len(values) != indexFields {
+		return "", meta, false
+	}
+	if meta.Size, err = strconv.ParseInt(values[0], 10, 64); err != nil {
+		return "", meta, false
+	}
+	if meta.ModTime, err = strconv.ParseInt(values[1], 10, 64); err != nil {
+		return "", meta, false
+	}
+	if meta.Inode, err = strconv.ParseUint(values[2], 10, 64); err != nil {
+		return "", meta, false
+	}
+	if meta.HashedAt, err = strconv.ParseInt(values[3], 10, 64); err != nil {
+		return "", meta, false
+	}
+	fileName, err := unescapeFileName(values[indexFields-1])
+	if err != nil {
+		return "", meta, false
+	}
+	return fileName, meta, true
+}
+
+// Saves the map to the index atomically, sorted by relative path.
+func SaveIndex(outputFile string, data map[string]FileMeta) error {
+
+	var (
+		keys []string = make([]string, 0, len(data))
+	)
+
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return writeFileAtomically(outputFile, 0644, func(writer *bufio.Writer) error {
+		if _, err := writer.WriteString(indexHeader + "\n"); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			fileName, _ := escapeFileName(key)
+			meta := data[key]
+			if _, err := fmt.Fprintf(writer, "%d\t%d\t%d\t%d\t%s\n", meta.Size, meta.ModTime, meta.Inode, meta.HashedAt, fileName); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
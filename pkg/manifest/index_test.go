@@ -0,0 +1,115 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexFilepath(t *testing.T) {
+	if got := IndexFilepath("/home/lukasz/test.xxhsum"); got != "/home/lukasz/test.xxhsum.meta" {
+		t.Errorf("IndexFilepath() = %v, want %v", got, "/home/lukasz/test.xxhsum.meta")
+	}
+}
+
+func TestLoadIndex(t *testing.T) {
+	type args struct {
+		content string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]FileMeta
+		wantErr bool
+	}{
+		{"EMPTY", args{""}, map[string]FileMeta{}, false},
+		{"ENTRIES", args{indexHeader + "\n12\t1700000000000000000\t42\t1700000000500000000\ta\n0\t1\t0\t0\tsub/b c\n"},
+			map[string]FileMeta{"a": {12, 1700000000000000000, 42, 1700000000500000000}, "sub/b c": {0, 1, 0, 0}}, false},
+		{"NO_HEADER", args{"1\t2\t3\t4\ta\n"}, map[string]FileMeta{"a": {1, 2, 3, 4}}, false},
+		{"PATH_WITH_TAB", args{"1\t2\t3\t4\tb\tc\n"}, map[string]FileMeta{"b\tc": {1, 2, 3, 4}}, false},
+		{"MALFORMED", args{"x\t2\t3\t4\ta\n1\tx\t3\t4\tb\n1\t2\tx\t4\tc\n1\t2\t3\tx\td\n1\t2\t3\n12\t1\te\n\n5\t6\t7\t8\tf\n"},
+			map[string]FileMeta{"f": {5, 6, 7, 8}}, false},
+		{"ESCAPED_PATH", args{"1\t2\t3\t4\ta\\nb\\\\c\n5\t6\t7\t8\tbad\\x\n"}, map[string]FileMeta{"a\nb\\c": {1, 2, 3, 4}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputFile := filepath.Join(t.TempDir(), "test.xxhsum.meta")
+			if err := os.WriteFile(inputFile, []byte(tt.args.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadIndex(inputFile)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadIndex() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadIndex() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, err := LoadIndex(filepath.Join(t.TempDir(), "missing.meta")); err != nil || len(got) != 0 {
+		t.Errorf("LoadIndex() = %v, %v, want empty map", got, err)
+	}
+}
+
+func TestSaveIndex(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "test.xxhsum.meta")
+	data := map[string]FileMeta{"sub/b": {0, 1, 0, 0}, "a": {12, 1700000000000000000, 42, 1700000000500000000}, "sub/c\nd": {2, 3, 4, 5}}
+
+	if err := SaveIndex(outputFile, data); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+	if got, err := os.ReadFile(outputFile); err != nil {
+		t.Fatal(err)
+	} else if want := "# size\tmtime\tinode\thashed\tpath\n12\t1700000000000000000\t42\t1700000000500000000\ta\n0\t1\t0\t0\tsub/b\n2\t3\t4\t5\tsub/c\\nd\n"; string(got) != want {
+		t.Errorf("SaveIndex() wrote %q, want %q", got, want)
+	}
+
+	if got, err := LoadIndex(outputFile); err != nil || !reflect.DeepEqual(got, data) {
+		t.Errorf("LoadIndex() = %v, %v, want %v", got, err, data)
+	}
+}
+
+func TestFileMeta_Unchanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		m       FileMeta
+		current FileMeta
+		want    bool
+	}{
+		{"SAME", FileMeta{1, 2, 3, 4}, FileMeta{1, 2, 3, 0}, true},
+		{"SIZE", FileMeta{1, 2, 3, 4}, FileMeta{9, 2, 3, 0}, false},
+		{"MTIME", FileMeta{1, 2, 3, 4}, FileMeta{1, 9, 3, 0}, false},
+		{"INODE", FileMeta{1, 2, 3, 4}, FileMeta{1, 2, 9, 0}, false},
+		{"INODE_UNKNOWN", FileMeta{1, 2, 0, 0}, FileMeta{1, 2, 9, 0}, true},
+		{"INODE_UNAVAILABLE", FileMeta{1, 2, 3, 4}, FileMeta{1, 2, 0, 0}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Unchanged(tt.current); got != tt.want {
+				t.Errorf("FileMeta.Unchanged() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewFileMeta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a")
+	if err := os.WriteFile(path, []byte("Lorem ipsum\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := NewFileMeta(fileInfo)
+	if got.Size != 12 || got.ModTime != fileInfo.ModTime().UnixNano() || got.HashedAt != 0 {
+		t.Errorf("NewFileMeta() = %+v, want size 12, mtime %v and no time hashed at", got, fileInfo.ModTime().UnixNano())
+	}
+	if got.Inode != inode(fileInfo) {
+		t.Errorf("NewFileMeta() inode = %v, want %v", got.Inode, inode(fileInfo))
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package manifest
+
+import "io/fs"
+
+// Outputs 0, as inode numbers are not available on this platform.
+func inode(fileInfo fs.FileInfo) uint64 {
+	return 0
+}
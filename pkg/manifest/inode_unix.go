@@ -0,0 +1,16 @@
+//go:build unix
+
+package manifest
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Outputs the inode number of the file described by `fileInfo`, 0 if unknown.
+func inode(fileInfo fs.FileInfo) uint64 {
+	if stat, ok := fileInfo.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
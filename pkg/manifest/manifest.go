@@ -0,0 +1,181 @@
+// Package manifest reads, writes and appends to xxhsum files, as written by `xxhsum` and `append-xxhsum`.
+//
+// A manifest lists files with their hashes, one entry per line, in GNU-style or BSD-style lines:
+//
+//	0d3148243051664f *Photos/a.jpg
+//	XXH64 (Photos/a.jpg) = 0d3148243051664f
+//
+// File names are relative to the directory of the manifest. See `Manifest` for a loaded manifest, `Reader` and `Scan`
+// for reading one entry at a time, `Writer`, `Appender` and `Append` for writing entries, `Rewrite` for changing them,
+// `Verify` for checking files against them, `Hasher` for hashing many files in parallel, and `Walk` and `Search`
+// for finding files to be added.
+package manifest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Number of lines `LoadContext` reads between checks of its context.
+const loadCheckLines int = 4096
+
+// Entries of a manifest, by file name.
+//
+// A nil `*Manifest` is an empty one, so that a manifest not created yet needs no special case.
+type Manifest struct {
+	entries map[string]Entry // `entries` maps file names in canonical form to their entries.
+}
+
+// Outputs an empty manifest.
+func New() *Manifest {
+	return &Manifest{entries: make(map[string]Entry)}
+}
+
+// Loads the manifest at `inputFile`, with lines terminated by NUL if `zero`, and file names not escaped then.
+// The style is detected line by line, so manifests mixing GNU-style and BSD-style lines load fully.
+// A last line lacking its terminator, e.g. after an interrupted append, is not loaded, as it may be cut short.
+// A file name listed more than once keeps its last entry. The report tells malformed and repeated lines, see `Report`,
+// and lines terminated the other way than `zero` tells. Entries of unrecognised hashes are kept, see `Entry.Recognised`.
+func Load(inputFile string, zero bool) (*Manifest, Report, error) {
+	return LoadContext(context.Background(), inputFile, zero)
+}
+
+// Loads the manifest at `inputFile` as `Load` does. Loading stops once `ctx` is done, outputting its error.
+func LoadContext(ctx context.Context, inputFile string, zero bool) (*Manifest, Report, error) {
+
+	var (
+		file    *os.File       = nil
+		scanner *bufio.Scanner = nil
+		err     error          = nil
+		m       *Manifest      = New()
+		lines   map[string]int = make(map[string]int) // `lines` holds the number of the line listing each file name last.
+		number  int            = 0
+		tail    string         = "" // `tail` is the last line if it lacks its terminator.
+		report  Report         = Report{}
+	)
+
+	if file, err = os.Open(inputFile); err != nil {
+		return nil, report, fmt.Errorf("error opening file: %s; %w", inputFile, err)
+	}
+	defer file.Close()
+
+	// Read the file line by line
+	scanner = bufio.NewScanner(file)
+	scanner.Split(scanTerminated(zero, &tail))
+	for scanner.Scan() {
+		number++
+		if number%loadCheckLines == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, report, err
+			}
+		}
+		line := scanner.Text()
+		if !zero && strings.Contains(line, "\x00") {
+			report.Mismatched = true
+		}
+		fileName, checksum, style := parseLine(line, zero)
+
+		if isMalformed(line, checksum, style) {
+			report.Malformed++
+			report.Diagnostics = append(report.Diagnostics, Diagnostic{Line: number, Kind: DiagnosticMalformed})
+		}
+		switch style {
+		case BSD:
+			report.BSDLines++
+		case GNU:
+			report.GNULines++
+		default:
+			continue
+		}
+
+		key := CanonicalPath(fileName)
+		if previous, ok := lines[key]; ok {
+			diagnostic := Diagnostic{Line: number, Kind: DiagnosticDuplicate, FileName: key, Previous: previous}
+			if !strings.EqualFold(m.entries[key].Checksum(), checksum) {
+				diagnostic.Kind = DiagnosticConflicting
+				report.Conflicting++
+			} else {
+				report.Duplicate++
+			}
+			report.Diagnostics = append(report.Diagnostics, diagnostic)
+		}
+		lines[key] = number
+		m.entries[key], _ = NewEntry(key, checksum)
+	}
+
+	// Check for any scanning errors
+	if err := scanner.Err(); err != nil {
+		return nil, report, fmt.Errorf("error scanning: %s; %w", inputFile, err)
+	}
+
+	report.Unterminated = tail != ""
+	switch true {
+	case !zero && strings.Contains(tail, "\x00"):
+		// NUL in the incomplete line only.
+		report.Mismatched = true
+	case zero && number == 0 && strings.Contains(tail, "\n"):
+		// No NUL at all, but line breaks.
+		report.Mismatched = true
+	}
+
+	return m, report, nil
+}
+
+// Adds the entry `e`, replacing the entry of the same file name if any.
+func (m *Manifest) Add(e Entry) {
+	if m.entries == nil {
+		m.entries = make(map[string]Entry)
+	}
+	e.Path = CanonicalPath(e.Path)
+	m.entries[e.Path] = e
+}
+
+// Outputs the entry of file name `path`, and if there is one. File names are compared in canonical form.
+func (m *Manifest) Lookup(path string) (Entry, bool) {
+	if m == nil {
+		return Entry{}, false
+	}
+	e, ok := m.entries[CanonicalPath(path)]
+	return e, ok
+}
+
+// Outputs the number of entries.
+func (m *Manifest) Len() int {
+	if m == nil {
+		return 0
+	}
+	return len(m.entries)
+}
+
+// Outputs file names of all entries in sorted order.
+func (m *Manifest) Paths() []string {
+
+	var (
+		paths []string = make([]string, 0, m.Len())
+	)
+
+	if m != nil {
+		for path := range m.entries {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Outputs all entries sorted by file name.
+func (m *Manifest) Entries() []Entry {
+
+	var (
+		entries []Entry = make([]Entry, 0, m.Len())
+	)
+
+	for _, path := range m.Paths() {
+		entries = append(entries, m.entries[path])
+	}
+	return entries
+}
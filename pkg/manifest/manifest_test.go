@@ -0,0 +1,257 @@
+package manifest
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+	content := "# c\n0d3148243051664f *./b\nXXH3 (a) = 2d06800538d394c2\n123 *c\n0000000000000000 *b\n"
+	if err := os.WriteFile(inputFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, report, err := Load(inputFile, false)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []Entry{{"a", "2d06800538d394c2", XXH3}, {"b", "0000000000000000", XXH64}, {Path: "c", Hash: "123"}}
+	if got := m.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() entries = %+v, want %+v", got, want)
+	}
+	if report.Malformed != 1 || report.Conflicting != 1 {
+		t.Errorf("Load() report = %+v, want 1 malformed and 1 conflicting line", report)
+	}
+
+	if _, _, err := Load(filepath.Join(t.TempDir(), "missing.xxhsum"), false); err == nil {
+		t.Errorf("Load() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestManifest(t *testing.T) {
+	m := New()
+	m.Add(Entry{"./b//c", "0d3148243051664f", XXH64})
+	m.Add(Entry{"a", "02cc5d05", XXH32})
+	m.Add(Entry{"a", "2d06800538d394c2", XXH3})
+
+	if got := m.Len(); got != 2 {
+		t.Errorf("Manifest.Len() = %v, want %v", got, 2)
+	}
+	if got := m.Paths(); !reflect.DeepEqual(got, []string{"a", "b/c"}) {
+		t.Errorf("Manifest.Paths() = %v, want %v", got, []string{"a", "b/c"})
+	}
+	if got, ok := m.Lookup("b/./c"); !ok || got.Path != "b/c" {
+		t.Errorf("Manifest.Lookup() = %+v, %v, want entry of b/c", got, ok)
+	}
+	if got, _ := m.Lookup("a"); got.Algorithm != XXH3 {
+		t.Errorf("Manifest.Lookup() = %+v, want the entry added last", got)
+	}
+
+	// A nil manifest is an empty one.
+	var empty *Manifest
+	if _, ok := empty.Lookup("a"); ok || empty.Len() != 0 || len(empty.Paths()) != 0 || len(empty.Entries()) != 0 {
+		t.Errorf("nil Manifest is not empty")
+	}
+}
+
+func TestLoad_files(t *testing.T) {
+	type args struct {
+		inputFile string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    map[string]string
+		want1   Style
+		wantErr bool
+	}{
+		{"NO_FILE1", args{"/Bulba"}, nil, "", true},
+		{"WRONG_FILE1", args{"../../go.mod"}, make(map[string]string), "", false},
+		{"WRONG_FILE2", args{"../../LICENSE"}, make(map[string]string), "", false},
+		{"FILEA", args{"../../tst/test1.xxhsum"}, map[string]string{
+			"golang/goroot/go.mod": "1f809539dbc4e242", "golang/goroot/sample-app/sample-app": "76b9b81ec1c51248"},
+			GNU, false},
+		{"FILEB", args{"../../tst/test2.xxhsum"}, map[string]string{
+			"golang/goroot/go.mod": "1f809539dbc4e242", "golang/goroot/sample-app/sample-app": "76b9b81ec1c51248"},
+			BSD, false},
+		{"ALGORITHMS_BSD", args{"../../tst/test3.xxhsum"}, map[string]string{
+			"a.txt": "02cc5d05", "b.txt": "ef46db3751d8e999", "c.txt": "XXH3_2d06800538d394c2", "d (1).txt": "99aa06d3014798d86001c324468d497f"},
+			BSD, false},
+		{"ALGORITHMS_GNU", args{"../../tst/test4.xxhsum"}, map[string]string{
+			"a.txt": "02cc5d05", "b.txt": "ef46db3751d8e999", "c.txt": "XXH3_2d06800538d394c2", "d (1).txt": "99aa06d3014798d86001c324468d497f"},
+			GNU, false},
+		{"MIXED", args{"../../tst/test5.xxhsum"}, map[string]string{
+			"a.txt": "02cc5d05", "b.txt": "ef46db3751d8e999", "c.txt": "XXH3_2d06800538d394c2", "d (1).txt": "99aa06d3014798d86001c324468d497f"},
+			Mixed, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, got1, err := Load(tt.args.inputFile, false)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got := checksums(m); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() got = %v, want %v", got, tt.want)
+			}
+			if got1.Style() != tt.want1 {
+				t.Errorf("Load() got1.Style() = %v, want %v", got1.Style(), tt.want1)
+			}
+		})
+	}
+}
+
+func TestLoad_zero(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+	content := "0000000000000001 *a\nb\x00XXH64 (./c\\d) = 0000000000000002\x00# comment\x00XXH3_0000000000000003 *e"
+	if err := os.WriteFile(inputFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The final line lacks its terminator, so it is not loaded.
+	want := map[string]string{"a\nb": "0000000000000001", "c\\d": "0000000000000002"}
+	m, report, err := Load(inputFile, true)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := checksums(m); !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() got = %q, want %q", got, want)
+	}
+	if report.Style() != Mixed {
+		t.Errorf("Load() got1.Style() = %v, want %v", report.Style(), Mixed)
+	}
+
+	// Rewriting keeps NUL terminators, and the final line lacking its terminator as it is.
+	if err := Rewrite(inputFile, true, func(line string, e Entry, style Style) string {
+		if e.Path == "c\\d" {
+			return ""
+		}
+		return line
+	}); err != nil {
+		t.Fatalf("Rewrite() error = %v", err)
+	}
+	if got, err := os.ReadFile(inputFile); err != nil {
+		t.Fatal(err)
+	} else if want := "0000000000000001 *a\nb\x00# comment\x00XXH3_0000000000000003 *e"; string(got) != want {
+		t.Errorf("Rewrite() wrote %q, want %q", got, want)
+	}
+}
+
+func TestLoad_report(t *testing.T) {
+	type args struct {
+		content string
+		zero    bool
+	}
+	tests := []struct {
+		name string
+		args args
+		want Report
+	}{
+		{"EMPTY", args{"", false}, Report{}},
+		{"CLEAN", args{"# c\n\n0000000000000001 *a\n", false}, Report{GNULines: 1}},
+		{"HALF_HASH", args{"0000000000000001 *a\n00000000", false}, Report{GNULines: 1, Unterminated: true}},
+		{"HALF_PATH", args{"0000000000000001 *a\n0000000000000002 *b/c", false}, Report{GNULines: 1, Unterminated: true}},
+		{"UNRECOGNISED_HASH", args{"00000 *a\nXXH64 (b) = 0001\n", false}, Report{GNULines: 1, BSDLines: 1, Malformed: 2,
+			Diagnostics: []Diagnostic{{Line: 1, Kind: DiagnosticMalformed}, {Line: 2, Kind: DiagnosticMalformed}}}},
+		{"TAG_WIDTH_MISMATCH", args{"XXH32 (a) = 0000000000000001\nXXH64 (b) = 00000001\n", false}, Report{BSDLines: 2, Malformed: 2,
+			Diagnostics: []Diagnostic{{Line: 1, Kind: DiagnosticMalformed}, {Line: 2, Kind: DiagnosticMalformed}}}},
+		{"GARBAGE", args{"garbage\n0000000000000001 *a\n", false}, Report{GNULines: 1, Malformed: 1,
+			Diagnostics: []Diagnostic{{Line: 1, Kind: DiagnosticMalformed}}}},
+		{"DUPLICATE", args{"0000000000000001 *a\n# c\nXXH64 (./a) = 0000000000000001\n", false}, Report{GNULines: 1, BSDLines: 1, Duplicate: 1,
+			Diagnostics: []Diagnostic{{Line: 3, Kind: DiagnosticDuplicate, FileName: "a", Previous: 1}}}},
+		{"CONFLICTING", args{"0000000000000001 *a\n0000000000000002 *b\n0000000000000003 *a\n0000000000000001 *a\n", false}, Report{GNULines: 4, Conflicting: 2,
+			Diagnostics: []Diagnostic{
+				{Line: 3, Kind: DiagnosticConflicting, FileName: "a", Previous: 1},
+				{Line: 4, Kind: DiagnosticConflicting, FileName: "a", Previous: 3}}}},
+		{"ZERO_CLEAN", args{"0000000000000001 *a\x00", true}, Report{GNULines: 1}},
+		{"ZERO_UNTERMINATED", args{"0000000000000001 *a\x0000000", true}, Report{GNULines: 1, Unterminated: true}},
+		{"NUL_IN_NEWLINE_MODE", args{"0000000000000001 *a\x000000000000000002 *b\x00", false}, Report{Unterminated: true, Mismatched: true}},
+		{"NEWLINE_IN_ZERO_MODE", args{"0000000000000001 *a\n0000000000000002 *b\n", true}, Report{Unterminated: true, Mismatched: true}},
+		{"ZERO_LINE_BREAK_IN_NAME", args{"0000000000000001 *a\nb\x00", true}, Report{GNULines: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+			if err := os.WriteFile(inputFile, []byte(tt.args.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, got, err := Load(inputFile, tt.args.zero); err != nil {
+				t.Errorf("Load() error = %v", err)
+			} else if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Load() got1 = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadContext(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+	content := strings.Repeat("0000000000000001 *a\n", loadCheckLines)
+	if err := os.WriteFile(inputFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if m, _, err := LoadContext(ctx, inputFile, false); m != nil || !errors.Is(err, context.Canceled) {
+		t.Errorf("LoadContext() = %v, %v, want nothing loaded and %v", m, err, context.Canceled)
+	}
+	if m, _, err := LoadContext(context.Background(), inputFile, false); err != nil || m.Len() != 1 {
+		t.Errorf("LoadContext() = %v, %v, want 1 entry", m, err)
+	}
+}
+
+// Writes a synthetic manifest of `count` lines, alternating GNU-style and BSD-style ones.
+func writeLargeManifest(b *testing.B, count int) string {
+
+	inputFile := filepath.Join(b.TempDir(), "large.xxhsum")
+	file, err := os.Create(inputFile)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	writer.WriteString("# XXH64 hashes https://xxhash.com/\n")
+	for i := 0; i < count; i++ {
+		if i%2 == 0 {
+			fmt.Fprintf(writer, "%016x *dir%03d/sub (%d)/file%07d.jpg\n", i, i%1000, i%7, i)
+		} else {
+			fmt.Fprintf(writer, "XXH64 (dir%03d/sub (%d)/file%07d.jpg) = %016x\n", i%1000, i%7, i, i)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	return inputFile
+}
+
+func BenchmarkLoad(b *testing.B) {
+	inputFile := writeLargeManifest(b, 100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Load(inputFile, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Outputs checksums of the entries of `m` by file name, as GNU-style lines have them, or nil if `m` is nil.
+func checksums(m *Manifest) map[string]string {
+	if m == nil {
+		return nil
+	}
+	got := make(map[string]string)
+	for _, entry := range m.Entries() {
+		got[entry.Path] = entry.Checksum()
+	}
+	return got
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package manifest
+
+import "os"
+
+// Opens the file at `filePath` for reading, as opening files does not block on this platform.
+func openFile(filePath string) (*os.File, error) {
+	return os.Open(filePath)
+}
@@ -0,0 +1,13 @@
+//go:build unix
+
+package manifest
+
+import (
+	"os"
+	"syscall"
+)
+
+// Opens the file at `filePath` for reading without blocking, so that named pipes without writers are opened at once.
+func openFile(filePath string) (*os.File, error) {
+	return os.OpenFile(filePath, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+}
@@ -0,0 +1,171 @@
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// Name of the file holding exclude patterns for its directory and below.
+const IgnoreFilename string = ".xxhsumignore"
+
+// Ordered gitignore-style patterns, matched against slash-separated paths relative to the directory they belong to.
+//
+// Supported are `#` comments, `!` negation, trailing `/` for directories only, leading or middle `/` anchoring
+// the pattern to its directory, `*`, `?` and `[...]` wildcards within a path segment, and `**` across segments.
+// Patterns without a slash match the name at any depth. The last matching pattern decides.
+type Patterns struct {
+	list []pattern
+}
+
+// A single gitignore-style pattern.
+type pattern struct {
+	negate   bool     // `negate` is set by a leading `!`.
+	dirOnly  bool     // `dirOnly` is set by a trailing `/`.
+	anchored bool     // `anchored` patterns match the whole relative path, others match the name only.
+	segments []string // `segments` are the slash-separated parts of the pattern.
+}
+
+// Parses pattern `lines`. Blank lines and comments are ignored.
+func NewPatterns(lines []string) *Patterns {
+
+	p := &Patterns{}
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var pt pattern
+
+		if strings.HasPrefix(line, "!") {
+			pt.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			// Escaped leading `!` or `#` is matched literally.
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			pt.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+
+		if strings.Contains(line, "/") {
+			pt.anchored = true
+			line = strings.TrimLeft(line, "/")
+		}
+
+		if line == "" {
+			continue
+		}
+		pt.segments = strings.Split(line, "/")
+		p.list = append(p.list, pt)
+	}
+	return p
+}
+
+// Loads patterns from the file, one per line.
+func LoadPatterns(inputFile string) (*Patterns, error) {
+
+	file, err := os.Open(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %s; %w", inputFile, err)
+	}
+	defer file.Close()
+
+	return readPatterns(file, inputFile)
+}
+
+// Loads patterns from the file `name` of `fsys`, one per line.
+func LoadPatternsFS(fsys fs.FS, name string) (*Patterns, error) {
+
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %s; %w", name, err)
+	}
+	defer file.Close()
+
+	return readPatterns(file, name)
+}
+
+// Reads patterns from `r`, one per line. `inputFile` names it in errors.
+func readPatterns(r io.Reader, inputFile string) (*Patterns, error) {
+
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning: %s; %w", inputFile, err)
+	}
+
+	return NewPatterns(lines), nil
+}
+
+// Outputs if there are no patterns.
+func (p *Patterns) Empty() bool {
+	return p == nil || len(p.list) == 0
+}
+
+// Matches slash-separated `relPath` of a file or a directory. Outputs if any pattern matched,
+// and if the last matching one was negated.
+func (p *Patterns) Match(relPath string, isDir bool) (matched bool, negated bool) {
+
+	if p == nil {
+		return false, false
+	}
+
+	names := strings.Split(strings.Trim(relPath, "/"), "/")
+
+	for _, pt := range p.list {
+		if pt.dirOnly && !isDir {
+			continue
+		}
+		if pt.anchored {
+			if !matchSegments(pt.segments, names) {
+				continue
+			}
+		} else {
+			if ok, _ := path.Match(pt.segments[0], names[len(names)-1]); !ok {
+				continue
+			}
+		}
+		matched, negated = true, pt.negate
+	}
+	return matched, negated
+}
+
+// Matches path `names` against pattern `segments`, with `**` matching any number of names.
+func matchSegments(segments []string, names []string) bool {
+
+	for len(segments) > 0 {
+		if segments[0] == "**" {
+			if len(segments) == 1 {
+				// Trailing `**` matches everything inside, but not the directory itself.
+				return len(names) > 0
+			}
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(segments[1:], names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(segments[0], names[0]); !ok {
+			return false
+		}
+		segments, names = segments[1:], names[1:]
+	}
+	return len(names) == 0
+}
@@ -0,0 +1,112 @@
+package manifest
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPatterns_Match(t *testing.T) {
+	type args struct {
+		lines   []string
+		relPath string
+		isDir   bool
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantMatched bool
+		wantNegated bool
+	}{
+		{"NO_PATTERNS", args{nil, "a.txt", false}, false, false},
+		{"COMMENTS_AND_BLANKS", args{[]string{"# a.txt", "", "   "}, "a.txt", false}, false, false},
+		{"NAME", args{[]string{".git"}, ".git", true}, true, false},
+		{"NAME_DEEP", args{[]string{"node_modules"}, "a/b/node_modules", true}, true, false},
+		{"WILDCARD", args{[]string{"*.swp"}, "a/.b.txt.swp", false}, true, false},
+		{"WILDCARD_NO_MATCH", args{[]string{"*.swp"}, "a/b.txt", false}, false, false},
+		{"DIR_ONLY_DIR", args{[]string{"cache/"}, "a/cache", true}, true, false},
+		{"DIR_ONLY_FILE", args{[]string{"cache/"}, "a/cache", false}, false, false},
+		{"ANCHORED_ROOT", args{[]string{"/build"}, "build", true}, true, false},
+		{"ANCHORED_DEEP", args{[]string{"/build"}, "a/build", true}, false, false},
+		{"ANCHORED_MIDDLE", args{[]string{"a/*.tmp"}, "a/x.tmp", false}, true, false},
+		{"ANCHORED_MIDDLE_DEEP", args{[]string{"a/*.tmp"}, "b/a/x.tmp", false}, false, false},
+		{"DOUBLESTAR_LEADING", args{[]string{"**/Thumbs.db"}, "a/b/Thumbs.db", false}, true, false},
+		{"DOUBLESTAR_LEADING_ROOT", args{[]string{"**/Thumbs.db"}, "Thumbs.db", false}, true, false},
+		{"DOUBLESTAR_TRAILING", args{[]string{"a/**"}, "a/b/c", false}, true, false},
+		{"DOUBLESTAR_TRAILING_ITSELF", args{[]string{"a/**"}, "a", true}, false, false},
+		{"DOUBLESTAR_MIDDLE", args{[]string{"a/**/z"}, "a/z", false}, true, false},
+		{"DOUBLESTAR_MIDDLE_DEEP", args{[]string{"a/**/z"}, "a/b/c/z", false}, true, false},
+		{"NEGATED", args{[]string{"*.log", "!keep.log"}, "keep.log", false}, true, true},
+		{"NEGATED_THEN_AGAIN", args{[]string{"*.log", "!keep.log", "keep.*"}, "keep.log", false}, true, false},
+		{"ESCAPED", args{[]string{`\!important`, `\#notes`}, "#notes", false}, true, false},
+		{"CHARACTER_CLASS", args{[]string{"IMG_[0-9][0-9].jpg"}, "x/IMG_01.jpg", false}, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotMatched, gotNegated := NewPatterns(tt.args.lines).Match(tt.args.relPath, tt.args.isDir)
+			if gotMatched != tt.wantMatched {
+				t.Errorf("Patterns.Match() gotMatched = %v, want %v", gotMatched, tt.wantMatched)
+			}
+			if gotNegated != tt.wantNegated {
+				t.Errorf("Patterns.Match() gotNegated = %v, want %v", gotNegated, tt.wantNegated)
+			}
+		})
+	}
+}
+
+func TestPatterns_Empty(t *testing.T) {
+	var nilPatterns *Patterns
+	if !nilPatterns.Empty() {
+		t.Errorf("Patterns.Empty() = %v, want %v", false, true)
+	}
+	if !NewPatterns([]string{"# comment"}).Empty() {
+		t.Errorf("Patterns.Empty() = %v, want %v", false, true)
+	}
+	if NewPatterns([]string{"*.tmp"}).Empty() {
+		t.Errorf("Patterns.Empty() = %v, want %v", true, false)
+	}
+}
+
+func TestLoadPatterns(t *testing.T) {
+	inputFile := filepath.Join(t.TempDir(), IgnoreFilename)
+	if err := os.WriteFile(inputFile, []byte("# thumbnails\r\n.thumbnails/\r\n*.swp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := LoadPatterns(inputFile)
+	if err != nil {
+		t.Fatalf("LoadPatterns() error = %v", err)
+	}
+	if matched, _ := p.Match("a/.thumbnails", true); !matched {
+		t.Errorf("LoadPatterns() did not load .thumbnails/")
+	}
+	if matched, _ := p.Match("a/b.swp", false); !matched {
+		t.Errorf("LoadPatterns() did not load *.swp")
+	}
+
+	if _, err := LoadPatterns(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("LoadPatterns() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestLoadPatternsFS(t *testing.T) {
+	fsys := fstest.MapFS{"sub/" + IgnoreFilename: {Data: []byte("# thumbnails\r\n.thumbnails/\r\n*.swp\n")}}
+
+	p, err := LoadPatternsFS(fsys, "sub/"+IgnoreFilename)
+	if err != nil {
+		t.Fatalf("LoadPatternsFS() error = %v", err)
+	}
+	if matched, _ := p.Match("a/.thumbnails", true); !matched {
+		t.Errorf("LoadPatternsFS() did not load .thumbnails/")
+	}
+	if matched, _ := p.Match("a/b.swp", false); !matched {
+		t.Errorf("LoadPatternsFS() did not load *.swp")
+	}
+
+	if _, err := LoadPatternsFS(fsys, IgnoreFilename); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("LoadPatternsFS() error = %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Error of a malformed line: neither an entry with a recognised hash, nor a comment, nor blank.
+type MalformedError struct {
+	Line int    // `Line` is the 1-based number of the line.
+	Text string // `Text` is the line without its terminator.
+}
+
+func (e *MalformedError) Error() string {
+	return fmt.Sprintf("line %d: malformed: %q", e.Line, e.Text)
+}
+
+// Reads entries of a manifest one at a time, from GNU-style and BSD-style lines alike.
+type Reader struct {
+	scanner *bufio.Scanner // `scanner` splits the input into lines.
+	zero    bool           // `zero` tells that lines are terminated by NUL, and file names are not escaped.
+	line    int            // `line` is the number of the line read last.
+	style   Style          // `style` is the style of the entry read last.
+}
+
+// Outputs a reader of manifest lines from `r`, terminated by NUL if `zero`.
+func NewReader(r io.Reader, zero bool) *Reader {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(ScanLines(zero))
+	return &Reader{scanner: scanner, zero: zero}
+}
+
+// Reads the next entry, skipping comments and blank lines. Its file name is in canonical form, see `CanonicalPath`.
+// Outputs `io.EOF` after the last one, and a `*MalformedError` for a malformed line, after which reading may go on.
+func (r *Reader) Read() (Entry, error) {
+
+	for r.scanner.Scan() {
+		r.line++
+		text := r.scanner.Text()
+
+		fileName, checksum, style := parseLine(text, r.zero)
+		if style == "" {
+			if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			return Entry{}, &MalformedError{Line: r.line, Text: text}
+		}
+
+		entry, err := NewEntry(CanonicalPath(fileName), checksum)
+		if err != nil {
+			return Entry{}, &MalformedError{Line: r.line, Text: text}
+		}
+		r.style = style
+		return entry, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return Entry{}, err
+	}
+	return Entry{}, io.EOF
+}
+
+// Outputs the 1-based number of the line read last.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Outputs the style of the line of the entry read last.
+func (r *Reader) Style() Style {
+	return r.style
+}
+
+// Reads all entries from `r`, terminated by NUL if `zero`, passing each to `fn` in file order.
+// Malformed lines are skipped. Scanning stops at the first error output by `fn`, which is output in turn.
+func Scan(r io.Reader, zero bool, fn func(Entry) error) error {
+
+	reader := NewReader(r, zero)
+	for {
+		entry, err := reader.Read()
+		switch err.(type) {
+		case nil:
+			if err := fn(entry); err != nil {
+				return err
+			}
+		case *MalformedError:
+			continue
+		default:
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
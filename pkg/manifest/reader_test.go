@@ -0,0 +1,82 @@
+package manifest
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReader_Read(t *testing.T) {
+	content := "# c\n\n0d3148243051664f *./a\ngarbage\n\\XXH3 (b\\nc) = 2d06800538d394c2\n00000"
+	r := NewReader(strings.NewReader(content), false)
+
+	want := []struct {
+		entry Entry
+		line  int
+		style Style
+		err   bool
+	}{
+		{Entry{"a", "0d3148243051664f", XXH64}, 3, GNU, false},
+		{Entry{}, 4, GNU, true},
+		{Entry{"b\nc", "2d06800538d394c2", XXH3}, 5, BSD, false},
+		{Entry{}, 6, BSD, true},
+	}
+	for _, w := range want {
+		entry, err := r.Read()
+		var malformed *MalformedError
+		if w.err != errors.As(err, &malformed) {
+			t.Fatalf("Reader.Read() error = %v, want malformed %v", err, w.err)
+		}
+		if entry != w.entry || r.Line() != w.line || r.Style() != w.style {
+			t.Errorf("Reader.Read() = %+v at line %d of %v, want %+v at line %d of %v", entry, r.Line(), r.Style(), w.entry, w.line, w.style)
+		}
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Reader.Read() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestReader_Read_zero(t *testing.T) {
+	r := NewReader(strings.NewReader("0d3148243051664f *a\nb\x00XXH64 (c\\d) = 0000000000000001\x00"), true)
+
+	got := []Entry{}
+	for {
+		entry, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Reader.Read() error = %v", err)
+		}
+		got = append(got, entry)
+	}
+	want := []Entry{{"a\nb", "0d3148243051664f", XXH64}, {"c\\d", "0000000000000001", XXH64}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Reader.Read() = %+v, want %+v", got, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	content := "0d3148243051664f *a\ngarbage\n02cc5d05 *b\n0000000000000001 *c\n"
+	stop := errors.New("stop")
+
+	got := []string{}
+	err := Scan(strings.NewReader(content), false, func(e Entry) error {
+		got = append(got, e.Path)
+		if e.Path == "b" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("Scan() error = %v, want %v", err, stop)
+	}
+	if !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Scan() passed %v, want %v", got, []string{"a", "b"})
+	}
+
+	if err := Scan(strings.NewReader(content), false, func(e Entry) error { return nil }); err != nil {
+		t.Errorf("Scan() error = %v, want %v", err, nil)
+	}
+}
@@ -0,0 +1,142 @@
+package manifest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rewrites the manifest at `inputFile` atomically, passing each entry through `rewrite`.
+//
+// `rewrite` gets the line, its entry with the file name in canonical form, and its style, and outputs the line to be
+// written instead, without terminator. Empty output drops the entry. Entries of unrecognised hashes are passed too,
+// see `Entry.Recognised`. Lines that are not entries, like comments, are kept. With `zero` lines are terminated by NUL.
+// A last line lacking its terminator, e.g. after an interrupted append, is kept as it is, as `Load` does not load it.
+func Rewrite(inputFile string, zero bool, rewrite func(line string, e Entry, style Style) string) error {
+	return rewriteLines(inputFile, zero, func(line string, terminated bool) (string, bool) {
+		if !terminated {
+			return line, true
+		}
+		if fileName, checksum, style := parseLine(line, zero); style != "" {
+			entry, _ := NewEntry(CanonicalPath(fileName), checksum)
+			line = rewrite(line, entry, style)
+			return line, line != ""
+		}
+		return line, true
+	})
+}
+
+// Removes the entries of file names `paths` from the manifest at `inputFile` atomically, see `Rewrite`.
+func Remove(inputFile string, zero bool, paths ...string) error {
+
+	var (
+		drop map[string]bool = make(map[string]bool, len(paths)) // `drop` holds file names of entries to be removed.
+	)
+
+	for _, path := range paths {
+		drop[CanonicalPath(path)] = true
+	}
+
+	return Rewrite(inputFile, zero, func(line string, e Entry, style Style) string {
+		if drop[e.Path] {
+			return ""
+		}
+		return line
+	})
+}
+
+// Replaces the entries of the file names of `entries` in the manifest at `inputFile` atomically, see `Rewrite`.
+// Each line keeps its style. File names not listed are not added.
+func Replace(inputFile string, zero bool, entries ...Entry) error {
+
+	var (
+		replace map[string]Entry = make(map[string]Entry, len(entries)) // `replace` holds the new entries by file name.
+	)
+
+	for _, entry := range entries {
+		entry.Path = CanonicalPath(entry.Path)
+		replace[entry.Path] = entry
+	}
+
+	return Rewrite(inputFile, zero, func(line string, e Entry, style Style) string {
+		if entry, ok := replace[e.Path]; ok {
+			return strings.TrimSuffix(entry.Line(style, zero), terminator(zero))
+		}
+		return line
+	})
+}
+
+// Rewrites the manifest at `inputFile` atomically without malformed lines, see `Report`, nor a last line lacking its
+// terminator, e.g. after an interrupted append. Outputs the lines removed.
+func Repair(inputFile string, zero bool) ([]string, error) {
+
+	var (
+		removed []string = []string{}
+	)
+
+	err := rewriteLines(inputFile, zero, func(line string, terminated bool) (string, bool) {
+		if _, checksum, style := parseLine(line, zero); !terminated || isMalformed(line, checksum, style) {
+			removed = append(removed, line)
+			return "", false
+		}
+		return line, true
+	})
+	return removed, err
+}
+
+// `rewriteLines` rewrites the manifest at `inputFile` atomically, passing each line through `rewrite`, telling if it is
+// terminated. It outputs the line to be written instead, and if it is to be written at all. A line written is terminated
+// as the line it replaces is.
+func rewriteLines(inputFile string, zero bool, rewrite func(line string, terminated bool) (string, bool)) error {
+
+	var (
+		file     *os.File    = nil
+		fileInfo os.FileInfo = nil
+		err      error       = nil
+	)
+
+	// Open the text file
+	if file, err = os.Open(inputFile); err != nil {
+		return fmt.Errorf("error opening file: %s; %w", inputFile, err)
+	}
+	defer file.Close()
+
+	if fileInfo, err = file.Stat(); err != nil {
+		return fmt.Errorf("error accessing file: %s; %w", inputFile, err)
+	}
+
+	return writeFileAtomically(inputFile, fileInfo.Mode().Perm(), func(writer *bufio.Writer) error {
+		var (
+			tail string = "" // `tail` is the last line if it lacks its terminator.
+		)
+
+		// Copy the file line by line
+		scanner := bufio.NewScanner(file)
+		scanner.Split(scanTerminated(zero, &tail))
+		for scanner.Scan() {
+			line, keep := rewrite(scanner.Text(), true)
+			if !keep {
+				continue
+			}
+			if _, err := writer.WriteString(line + terminator(zero)); err != nil {
+				return err
+			}
+		}
+
+		// Check for any scanning errors
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("error scanning: %s; %w", inputFile, err)
+		}
+
+		if tail == "" {
+			return nil
+		}
+		if line, keep := rewrite(tail, false); keep {
+			if _, err := writer.WriteString(line); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
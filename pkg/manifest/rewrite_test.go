@@ -0,0 +1,185 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	type args struct {
+		content string
+		zero    bool
+	}
+	tests := []struct {
+		name        string
+		args        args
+		want        string
+		wantRemoved []string
+	}{
+		{"CLEAN", args{"# c\n\n0000000000000001 *a\n", false}, "# c\n\n0000000000000001 *a\n", []string{}},
+		{"HALF_HASH", args{"0000000000000001 *a\n00000000", false}, "0000000000000001 *a\n", []string{"00000000"}},
+		{"UNTERMINATED_ENTRY", args{"0000000000000001 *a\n0000000000000002 *b", false}, "0000000000000001 *a\n", []string{"0000000000000002 *b"}},
+		{"MALFORMED_INSIDE", args{"garbage\n0000000000000001 *a\n00000 *b\n", false}, "0000000000000001 *a\n", []string{"garbage", "00000 *b"}},
+		{"ZERO", args{"0000000000000001 *a\x0000000", true}, "0000000000000001 *a\x00", []string{"00000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+			if err := os.WriteFile(inputFile, []byte(tt.args.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			gotRemoved, err := Repair(inputFile, tt.args.zero)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+			if !reflect.DeepEqual(gotRemoved, tt.wantRemoved) {
+				t.Errorf("Repair() = %q, want %q", gotRemoved, tt.wantRemoved)
+			}
+			if got, err := os.ReadFile(inputFile); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Errorf("Repair() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	type args struct {
+		content string
+		rewrite func(line string, e Entry, style Style) string
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{"KEEP_ALL", args{"# c\n1 *a\nXXH64 (b) = 2\n", func(line string, e Entry, style Style) string { return line }},
+			"# c\n1 *a\nXXH64 (b) = 2\n", false},
+		{"DROP_ALL", args{"# c\n1 *a\nXXH64 (b) = 2\n", func(line string, e Entry, style Style) string { return "" }},
+			"# c\n", false},
+		{"REPLACE", args{"1 *a\n2 *b\n", func(line string, e Entry, style Style) string {
+			if e.Path == "b" {
+				return "3 *b"
+			}
+			return line
+		}}, "1 *a\n3 *b\n", false},
+		{"CANONICAL_FILE_NAME", args{"1 *./a\nXXH64 (.//b/) = 2\n", func(line string, e Entry, style Style) string {
+			if e.Path == "a" || e.Path == "b" {
+				return ""
+			}
+			return line
+		}}, "", false},
+		{"NO_TRAILING_NEWLINE", args{"1 *a\n2 *b", func(line string, e Entry, style Style) string { return "" }},
+			"2 *b", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			inputFile := filepath.Join(dir, "test.xxhsum")
+			if err := os.WriteFile(inputFile, []byte(tt.args.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if err := Rewrite(inputFile, false, tt.args.rewrite); (err != nil) != tt.wantErr {
+				t.Errorf("Rewrite() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got, err := os.ReadFile(inputFile); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Errorf("Rewrite() wrote %q, want %q", got, tt.want)
+			}
+			if fileInfo, err := os.Stat(inputFile); err != nil {
+				t.Fatal(err)
+			} else if fileInfo.Mode().Perm() != 0600 {
+				t.Errorf("Rewrite() mode = %v, want %v", fileInfo.Mode().Perm(), os.FileMode(0600))
+			}
+			if entries, err := os.ReadDir(dir); err != nil {
+				t.Fatal(err)
+			} else if len(entries) != 1 {
+				t.Errorf("Rewrite() left %d files behind, want 1", len(entries))
+			}
+		})
+	}
+
+	if err := Rewrite("/Bulba", false, func(line string, e Entry, style Style) string { return line }); err == nil {
+		t.Errorf("Rewrite() error = %v, wantErr %v", err, true)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	type args struct {
+		content string
+		paths   []string
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"NOTHING", args{"# c\n1 *a\n", []string{}}, "# c\n1 *a\n"},
+		{"GNU", args{"# c\n1 *a\n2 *b\n3 *c\n", []string{"b"}}, "# c\n1 *a\n3 *c\n"},
+		{"BSD", args{"XXH64 (a) = 1\nXXH64 (b) = 2\n", []string{"a", "b"}}, ""},
+		{"DUPLICATES", args{"1 *a\n2 *b\n1 *a\n", []string{"a"}}, "2 *b\n"},
+		{"CANONICAL_FILE_NAME", args{"# c\n0000000000000001 *./a\nXXH64 (b) = 0000000000000002\n0000000000000003 *c\n00000 *d\n", []string{"a", "./b/", "d"}},
+			"# c\n0000000000000003 *c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+			if err := os.WriteFile(inputFile, []byte(tt.args.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Remove(inputFile, false, tt.args.paths...); err != nil {
+				t.Errorf("Remove() error = %v", err)
+			}
+			if got, err := os.ReadFile(inputFile); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Errorf("Remove() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	type args struct {
+		content string
+		entries []Entry
+	}
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{"NOTHING", args{"# c\n1111111111111111 *a\n", []Entry{}}, "# c\n1111111111111111 *a\n"},
+		{"GNU", args{"# c\n1111111111111111 *a\n2222222222222222 *b\n", []Entry{{"b", "3333333333333333", XXH64}}},
+			"# c\n1111111111111111 *a\n3333333333333333 *b\n"},
+		{"BSD", args{"XXH64 (a) = 1111111111111111\n", []Entry{{"a", "3333333333333333", XXH64}}}, "XXH64 (a) = 3333333333333333\n"},
+		{"XXH3_MIXED", args{"XXH3 (a) = 1111111111111111\nXXH3_2222222222222222 *b\n", []Entry{{"a", "3333333333333333", XXH3}, {"b", "4444444444444444", XXH3}}},
+			"XXH3 (a) = 3333333333333333\nXXH3_4444444444444444 *b\n"},
+		// Lines keep their style, and file names not listed are not added.
+		{"CANONICAL_FILE_NAME", args{"# c\n0000000000000001 *a\nXXH64 (b) = 0000000000000002\n0000000000000003 *c\n",
+			[]Entry{{"./a", "2d06800538d394c2", XXH3}, {"b", "000000000000000b", XXH64}, {"e", "000000000000000e", XXH64}}},
+			"# c\nXXH3_2d06800538d394c2 *a\nXXH64 (b) = 000000000000000b\n0000000000000003 *c\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+			if err := os.WriteFile(inputFile, []byte(tt.args.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := Replace(inputFile, false, tt.args.entries...); err != nil {
+				t.Errorf("Replace() error = %v", err)
+			}
+			if got, err := os.ReadFile(inputFile); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Errorf("Replace() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
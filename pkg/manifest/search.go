@@ -0,0 +1,154 @@
+package manifest
+
+import (
+	"context"
+	"sync"
+)
+
+// Outcome of a file found by `Search`.
+type Finding int
+
+const (
+	FoundNew          Finding = iota // The file is not listed, and was hashed, see `Found.Entry`.
+	FoundUnhashed                    // The file is not listed, but was not hashed, as it is a dry run.
+	FoundListed                      // The file is listed, and was not re-hashed.
+	FoundModified                    // The file is listed, and its size, mtime or inode differ from the index, but it was not re-hashed.
+	FoundChanged                     // The file is listed, and was re-hashed to another hash, see `Found.Entry`.
+	FoundUnchanged                   // The file is listed, and was re-hashed to the same hash.
+	FoundUnrecognised                // The file is listed with a hash not recognised, so it was not re-hashed, see `Entry.Recognised`.
+	FoundUnreadable                  // The file failed to be hashed, see `Found.Err`.
+)
+
+// A file found by `Search`, with the outcome.
+type Found struct {
+	Finding  Finding // `Finding` is the outcome.
+	Path     string  // `Path` is the file name relative to the directory of the manifest, in canonical form.
+	Entry    Entry   // `Entry` is the new entry of a file hashed.
+	Previous Entry   // `Previous` is the entry listed for the file so far, with empty path if none.
+	Read     int64   // `Read` counts bytes read from a file hashed, also if hashing it failed.
+	Err      error   // `Err` is the error hashing the file, that of the context if hashing was abandoned.
+}
+
+// Outputs if the file was read to be hashed, whether hashing it succeeded or not.
+func (f Found) Hashed() bool {
+	switch f.Finding {
+	case FoundNew, FoundChanged, FoundUnchanged, FoundUnreadable:
+		return true
+	}
+	return false
+}
+
+// Settings of `Search` and `Prescan`.
+type SearchOptions struct {
+	WalkOptions
+	HashOptions
+	Algorithm Algorithm // `Algorithm` hashes files not listed.
+	Update    bool      // `Update` re-hashes listed files whose size, mtime or inode differ from the index, or are not in it.
+	Rehash    bool      // `Rehash` makes `Update` re-hash every listed file, regardless of the index.
+	DryRun    bool      // `DryRun` finds files not listed without hashing them.
+}
+
+// Walks the `roots` and the listed `files` as `Walk` does, hashing files not listed in `m`, and passes each file found
+// to `fn`. Files are hashed by a `Hasher` of `opts.HashOptions`, and calls of `fn` never overlap.
+//
+// With `opts.Update`, listed files are re-hashed with their algorithm if their size, mtime or inode differ from those
+// in the `index`. Without it, such files are found modified, without being read.
+// The `index` is updated with every file hashed, once all of them are, unless it is nil.
+// Once `ctx` is done, walking stops and hashing is abandoned, outputting its error. Files hashed by then were passed to `fn`.
+func Search(ctx context.Context, roots []Root, files []string, m *Manifest, index map[string]FileMeta, opts SearchOptions, fn func(Found)) error {
+
+	var (
+		mu      sync.Mutex          = sync.Mutex{}              // `mu` keeps calls of `fn` from overlapping.
+		indexed map[string]FileMeta = make(map[string]FileMeta) // `indexed` holds size, mtime and inode of files hashed.
+		hasher  *Hasher             = nil                       // `hasher` hashes files in parallel.
+	)
+
+	found := func(f Found) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn(f)
+	}
+
+	hasher = NewHasher(ctx, opts.HashOptions, func(result HashResult) {
+		f := Found{Path: result.Path, Entry: result.Entry, Previous: result.Previous, Read: result.Read, Err: result.Err}
+		switch true {
+		case result.Err != nil:
+			f.Finding = FoundUnreadable
+		case result.Previous.Path == "":
+			f.Finding = FoundNew
+		case result.Changed():
+			f.Finding = FoundChanged
+		default:
+			f.Finding = FoundUnchanged
+		}
+		if result.Err == nil {
+			indexed[result.Path] = result.Meta
+		}
+		found(f)
+	})
+
+	err := Walk(ctx, roots, files, opts.WalkOptions, func(file File) {
+		job, finding, hash := plan(file, m, index, opts)
+		if hash {
+			hasher.Hash(job)
+			return
+		}
+		found(Found{Finding: finding, Path: job.Path, Previous: job.Previous})
+	})
+
+	hasher.Close()
+
+	if index != nil {
+		// Merged only once all files are hashed, as the walk reads `index` while hashing.
+		for path, meta := range indexed {
+			index[path] = meta
+		}
+	}
+	return err
+}
+
+// Walks the `roots` and the listed `files` as `Search` does, passing the size of each file it would hash to `fn`.
+// Nothing is hashed, and paths skipped are not reported. Once `ctx` is done, walking stops, outputting its error.
+func Prescan(ctx context.Context, roots []Root, files []string, m *Manifest, index map[string]FileMeta, opts SearchOptions, fn func(size int64)) error {
+
+	opts.Skipped, opts.Errors = nil, nil
+
+	return Walk(ctx, roots, files, opts.WalkOptions, func(file File) {
+		if _, _, hash := plan(file, m, index, opts); hash {
+			fn(file.Info.Size())
+		}
+	})
+}
+
+// `plan` outputs the job hashing the `file`, and true if it is to be hashed. Otherwise, it outputs the finding.
+func plan(file File, m *Manifest, index map[string]FileMeta, opts SearchOptions) (HashJob, Finding, bool) {
+
+	var (
+		job HashJob = HashJob{Path: file.Path, FS: file.FS, Name: file.Name, Reader: file.Reader, Meta: NewFileMeta(file.Info)}
+	)
+
+	previous, listed := m.Lookup(file.Path)
+	if !listed {
+		if opts.DryRun {
+			return job, FoundUnhashed, false
+		}
+		job.Algorithm = opts.Algorithm
+		return job, FoundNew, true
+	}
+	job.Previous = previous
+
+	recorded, isRecorded := index[file.Path]
+	modified := isRecorded && !recorded.Unchanged(job.Meta)
+
+	switch true {
+	case opts.Update && (opts.Rehash || !isRecorded || modified) && !previous.Recognised():
+		return job, FoundUnrecognised, false
+	case opts.Update && (opts.Rehash || !isRecorded || modified):
+		// Size, mtime or inode differ from the recorded ones, or are not recorded. Re-hash it.
+		job.Algorithm = previous.Algorithm
+		return job, FoundChanged, true
+	case modified:
+		return job, FoundModified, false
+	}
+	return job, FoundListed, false
+}
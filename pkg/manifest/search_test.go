@@ -0,0 +1,108 @@
+package manifest
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// `searchFixture` outputs a root of new, listed, modified, changed and unrecognised files, the manifest listing them
+// and the index of the listed and modified ones.
+func searchFixture(t *testing.T) ([]Root, *Manifest, map[string]FileMeta) {
+	t.Helper()
+
+	fsys := fstest.MapFS{
+		"new":          {Data: []byte("Lorem ipsum\n")},
+		"listed":       {Data: []byte("Lorem ipsum\n")},
+		"modified":     {Data: []byte("Lorem ipsum\n")},
+		"changed":      {Data: []byte("Lorem ipsum\n")},
+		"unrecognised": {Data: []byte("Lorem ipsum\n")},
+	}
+	m := New()
+	for path, checksum := range map[string]string{"listed": "91a7667cd2256abd", "modified": "91a7667cd2256abd", "changed": "0000000000000000", "unrecognised": "xyz"} {
+		entry, _ := NewEntry(path, checksum)
+		m.Add(entry)
+	}
+	index := map[string]FileMeta{}
+	for _, name := range []string{"listed", "modified"} {
+		fileInfo, err := fs.Stat(fsys, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index[name] = NewFileMeta(fileInfo)
+	}
+	index["modified"] = FileMeta{Size: 1, ModTime: index["modified"].ModTime}
+
+	return []Root{{FS: fsys, Dir: t.TempDir()}}, m, index
+}
+
+func TestSearch(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want map[string]Finding
+	}{
+		{"APPEND", SearchOptions{}, map[string]Finding{"new": FoundNew, "listed": FoundListed, "modified": FoundModified, "changed": FoundListed, "unrecognised": FoundListed}},
+		{"DRY_RUN", SearchOptions{DryRun: true}, map[string]Finding{"new": FoundUnhashed, "listed": FoundListed, "modified": FoundModified, "changed": FoundListed, "unrecognised": FoundListed}},
+		{"UPDATE", SearchOptions{Update: true}, map[string]Finding{"new": FoundNew, "listed": FoundListed, "modified": FoundUnchanged, "changed": FoundChanged, "unrecognised": FoundUnrecognised}},
+		{"REHASH", SearchOptions{Update: true, Rehash: true}, map[string]Finding{"new": FoundNew, "listed": FoundUnchanged, "modified": FoundUnchanged, "changed": FoundChanged, "unrecognised": FoundUnrecognised}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, m, index := searchFixture(t)
+			tt.opts.Manifest = filepath.Join(roots[0].Dir, "test.xxhsum")
+			tt.opts.Algorithm = XXH64
+			tt.opts.Jobs = 2
+
+			got := map[string]Finding{}
+			err := Search(context.Background(), roots, nil, m, index, tt.opts, func(f Found) {
+				got[f.Path] = f.Finding
+				if f.Hashed() && (f.Err != nil || f.Entry.Hash != "91a7667cd2256abd" || f.Read != 12) {
+					t.Errorf("Search() found %+v, want hash 91a7667cd2256abd of 12 bytes", f)
+				}
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+			if _, ok := index["new"]; ok == tt.opts.DryRun {
+				t.Errorf("Search() index of new = %v, want it indexed once hashed", ok)
+			}
+			if index["modified"].Size != 12 && tt.opts.Update {
+				t.Errorf("Search() index of modified = %+v, want it updated once re-hashed", index["modified"])
+			}
+		})
+	}
+}
+
+func TestPrescan(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want int
+	}{
+		{"APPEND", SearchOptions{}, 1},
+		{"DRY_RUN", SearchOptions{DryRun: true}, 0},
+		{"UPDATE", SearchOptions{Update: true}, 3},
+		{"REHASH", SearchOptions{Update: true, Rehash: true}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, m, index := searchFixture(t)
+			tt.opts.Manifest = filepath.Join(roots[0].Dir, "test.xxhsum")
+
+			got, size := 0, int64(0)
+			if err := Prescan(context.Background(), roots, nil, m, index, tt.opts, func(n int64) { got, size = got+1, size+n }); err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || size != int64(12*tt.want) {
+				t.Errorf("Prescan() = %v files of %v bytes, want %v files of 12 bytes each", got, size, tt.want)
+			}
+		})
+	}
+}
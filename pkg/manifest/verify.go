@@ -0,0 +1,204 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Outcome of verifying an entry against the file it lists.
+type Status int
+
+const (
+	OK           Status = iota // The hash of the file matches the entry.
+	Failed                     // The hash of the file differs from the entry.
+	Missing                    // The file does not exist.
+	Unreadable                 // The file could not be opened or read, see `VerifyResult.Err`.
+	Unrecognised               // The hash of the entry is not recognised, so the file was not read, see `Entry.Recognised`.
+)
+
+// An entry with the outcome of verifying it.
+type VerifyResult struct {
+	Entry  Entry  // `Entry` is the entry verified.
+	Status Status // `Status` is the outcome.
+	Err    error  // `Err` is the error opening or reading the file, for `Unreadable` entries.
+}
+
+// Verifies every entry of `m` against the file it lists, relative to `dir`, passing the result of each to `fn`.
+// Each entry is re-hashed with the algorithm its hash was recorded with. Entries are verified in file name order.
+// With `archives`, entries of members of tar and zip archives, e.g. backup.tar/a.jpg, are read back through their archive,
+// which is read once for all of them, after the other entries.
+// Once `ctx` is done, verifying stops, outputting its error. Entries verified by then were passed to `fn`.
+func Verify(ctx context.Context, m *Manifest, dir string, archives bool, fn func(VerifyResult)) error {
+
+	entries, archived := splitArchived(m.Entries(), dir, archives)
+
+	for _, entry := range entries {
+		if !entry.Recognised() {
+			fn(VerifyResult{Entry: entry, Status: Unrecognised})
+			continue
+		}
+
+		checksum, err := HashFileContext(ctx, filepath.Join(dir, entry.Path), entry.Algorithm)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		fn(verified(entry, checksum, err))
+	}
+
+	for _, archive := range sortedKeys(archived) {
+		members := archived[archive]
+
+		err := WalkArchive(DirFS(filepath.Dir(archive)), filepath.Base(archive), func(member string, fileInfo fs.FileInfo, r io.Reader) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			entry, listed := members[member]
+			if !listed {
+				return nil
+			}
+			delete(members, member)
+
+			switch true {
+			case !entry.Recognised():
+				fn(VerifyResult{Entry: entry, Status: Unrecognised})
+			case !fileInfo.Mode().IsRegular():
+				fn(verified(entry, "", fmt.Errorf("not a regular file: %s", entry.Path)))
+			default:
+				checksum, err := HashContext(ctx, r, entry.Algorithm)
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				fn(verified(entry, checksum, err))
+			}
+			return nil
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			err = fmt.Errorf("error reading archive %s; %w", archive, err)
+		}
+
+		// Members not found in the archive, or not reached as it failed to be read.
+		for _, member := range sortedKeys(members) {
+			if err != nil {
+				fn(VerifyResult{Entry: members[member], Status: Unreadable, Err: err})
+			} else {
+				fn(VerifyResult{Entry: members[member], Status: Missing})
+			}
+		}
+	}
+
+	return nil
+}
+
+// `verified` outputs the result of the `entry`, whose file hashed to `checksum`, or failed to with `err`.
+func verified(entry Entry, checksum string, err error) VerifyResult {
+	switch true {
+	case errors.Is(err, fs.ErrNotExist):
+		return VerifyResult{Entry: entry, Status: Missing}
+	case err != nil:
+		return VerifyResult{Entry: entry, Status: Unreadable, Err: err}
+	case entry.Matches(checksum):
+		return VerifyResult{Entry: entry, Status: OK}
+	default:
+		return VerifyResult{Entry: entry, Status: Failed}
+	}
+}
+
+// Outputs file names of the entries of `m` whose files, relative to `dir`, no longer exist, in sorted order.
+// With `archives`, members of archives are looked up in their archive, which is read once for all of them.
+// Entries of files or archives that could not be accessed are kept, and the errors accessing them are output, joined.
+// Once `ctx` is done, searching stops, outputting the file names found by then and the error of `ctx`.
+func FindMissing(ctx context.Context, m *Manifest, dir string, archives bool) ([]string, error) {
+
+	var (
+		missing []string = []string{} // `missing` collects file names of files not found.
+		errs    []error  = nil        // `errs` collects errors of entries kept.
+	)
+
+	entries, archived := splitArchived(m.Entries(), dir, archives)
+
+	for _, archive := range sortedKeys(archived) {
+		members := archived[archive]
+
+		err := WalkArchive(DirFS(filepath.Dir(archive)), filepath.Base(archive), func(member string, fileInfo fs.FileInfo, r io.Reader) error {
+			delete(members, member)
+			return ctx.Err()
+		})
+		if ctx.Err() != nil {
+			sort.Strings(missing)
+			return missing, ctx.Err()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reading archive %s; keeping its members %w", archive, err))
+			continue
+		}
+		for _, entry := range members {
+			missing = append(missing, entry.Path)
+		}
+	}
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			sort.Strings(missing)
+			return missing, ctx.Err()
+		}
+		path := filepath.Join(dir, entry.Path)
+
+		if _, err := os.Lstat(path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				missing = append(missing, entry.Path)
+			} else {
+				errs = append(errs, fmt.Errorf("error accessing file %s; keeping %w", path, err))
+			}
+		}
+	}
+
+	sort.Strings(missing)
+	return missing, errors.Join(errs...)
+}
+
+// `splitArchived` separates `entries` of archive members from the others, if `archives` is given.
+// Entries of members are output by the path of their archive, joined to `dir`, and their slash-separated name within it.
+// Only file names leading through an existing archive file are members; others are output as they are.
+func splitArchived(entries []Entry, dir string, archives bool) ([]Entry, map[string]map[string]Entry) {
+
+	var (
+		plain    []Entry                     = []Entry{}                         // `plain` holds entries of files outside archives.
+		archived map[string]map[string]Entry = make(map[string]map[string]Entry) // `archived` holds entries of members, by archive.
+	)
+
+	for _, entry := range entries {
+		if archives {
+			path := filepath.Join(dir, entry.Path)
+			if archive, member, ok := SplitArchivePath(path); ok {
+				if fileInfo, err := os.Stat(archive); err == nil && fileInfo.Mode().IsRegular() {
+					if archived[archive] == nil {
+						archived[archive] = make(map[string]Entry)
+					}
+					archived[archive][member] = entry
+					continue
+				}
+			}
+		}
+		plain = append(plain, entry)
+	}
+	return plain, archived
+}
+
+// `sortedKeys` outputs keys of the map `m` in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
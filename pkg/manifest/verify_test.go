@@ -0,0 +1,138 @@
+package manifest
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Writes files of `content` by name under `dir`, and the tar archive backup.tar holding a and sub/b.
+func writeVerifyTree(t *testing.T, dir string, content map[string]string) {
+
+	for name, data := range content {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Create(filepath.Join(dir, "backup.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	writer := tar.NewWriter(file)
+	for _, name := range []string{"a", "sub/b"} {
+		if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 12, Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write([]byte("Lorem ipsum\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	writeVerifyTree(t, dir, map[string]string{"ok": "Lorem ipsum\n", "failed": "Lorem ipsum\n", "sub/dir/.keep": ""})
+
+	m := New()
+	for _, entry := range []Entry{
+		{"ok", "91a7667cd2256abd", XXH64},
+		{"failed", "0000000000000000", XXH64},
+		{"missing", "0000000000000000", XXH64},
+		{"sub/dir", "0000000000000000", XXH64},
+		{Path: "unrecognised", Hash: "123"},
+		{"backup.tar/a", "91a7667cd2256abd", XXH64},
+		{"backup.tar/sub/b", "0000000000000000", XXH64},
+		{"backup.tar/c", "0000000000000000", XXH64},
+	} {
+		m.Add(entry)
+	}
+
+	tests := []struct {
+		name     string
+		archives bool
+		want     map[string]Status
+	}{
+		{"PLAIN", false, map[string]Status{"ok": OK, "failed": Failed, "missing": Missing, "sub/dir": Unreadable, "unrecognised": Unrecognised,
+			"backup.tar/a": Unreadable, "backup.tar/sub/b": Unreadable, "backup.tar/c": Unreadable}},
+		{"ARCHIVES", true, map[string]Status{"ok": OK, "failed": Failed, "missing": Missing, "sub/dir": Unreadable, "unrecognised": Unrecognised,
+			"backup.tar/a": OK, "backup.tar/sub/b": Failed, "backup.tar/c": Missing}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]Status)
+			err := Verify(context.Background(), m, dir, tt.archives, func(result VerifyResult) {
+				if _, ok := got[result.Entry.Path]; ok {
+					t.Errorf("Verify() passed %s again", result.Entry.Path)
+				}
+				if (result.Status == Unreadable) != (result.Err != nil) {
+					t.Errorf("Verify() passed %s as %v with error %v", result.Entry.Path, result.Status, result.Err)
+				}
+				got[result.Entry.Path] = result.Status
+			})
+			if err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Nothing is verified once the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Verify(ctx, m, dir, true, func(result VerifyResult) {
+		if result.Status != Unrecognised {
+			t.Errorf("Verify() passed %s as %v after cancel", result.Entry.Path, result.Status)
+		}
+	}); err != context.Canceled {
+		t.Errorf("Verify() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFindMissing(t *testing.T) {
+	dir := t.TempDir()
+	writeVerifyTree(t, dir, map[string]string{"a": ""})
+
+	m := New()
+	for _, path := range []string{"a", "b", "backup.tar/a", "backup.tar/c", "other.tar/a"} {
+		m.Add(Entry{path, "0000000000000000", XXH64})
+	}
+
+	tests := []struct {
+		name     string
+		m        *Manifest
+		archives bool
+		want     []string
+		wantErr  bool
+	}{
+		{"EMPTY", New(), false, []string{}, false},
+		{"NIL", nil, false, []string{}, false},
+		// Members can't be looked up through their archive, so their entries are kept.
+		{"PLAIN", m, false, []string{"b", "other.tar/a"}, true},
+		{"ARCHIVES", m, true, []string{"b", "backup.tar/c", "other.tar/a"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindMissing(context.Background(), tt.m, dir, tt.archives)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FindMissing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindMissing() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// A directory tree to be walked, e.g. a directory on disk, an archive opened with `zip.OpenReader` or an `fstest.MapFS`.
+type Root struct {
+	FS  fs.FS  // `FS` holds the tree.
+	Dir string // `Dir` is the absolute path the tree is found at, which paths of its files are resolved against.
+}
+
+// Outputs roots of the directories at absolute `paths` on disk, opened by `DirFS`.
+func DirRoots(paths ...string) []Root {
+
+	var (
+		roots []Root = []Root{}
+	)
+
+	for _, path := range paths {
+		roots = append(roots, Root{FS: DirFS(path), Dir: path})
+	}
+	return roots
+}
+
+// Reason a path is skipped by `Walk`.
+type SkipReason int
+
+const (
+	SkipExcluded    SkipReason = iota // The path matches exclude or .xxhsumignore patterns.
+	SkipNotIncluded                   // The path matches no include pattern.
+	SkipNonRegular                    // The file is not regular, e.g. a symbolic link or a named pipe, see `Skip.Type`.
+	SkipDirectory                     // The listed path is a directory. Directories walked are not reported.
+	SkipCompanion                     // The file is the manifest or its companion, see `IsCompanionName`.
+)
+
+// A path skipped by `Walk`.
+type Skip struct {
+	Path   string     // `Path` is where the file is found.
+	Reason SkipReason // `Reason` tells why it is skipped.
+	Type   string     // `Type` is the type of a `SkipNonRegular` file, e.g. "named pipe".
+}
+
+// Settings of `Walk`.
+type WalkOptions struct {
+	Manifest string      // `Manifest` is the path of the manifest. Files are named relative to its directory, and it and its companions are skipped.
+	Excludes []string    // `Excludes` are gitignore-style patterns of paths to be skipped, relative to the root walked.
+	Includes []string    // `Includes` are gitignore-style patterns of the only paths to be walked, unless there are none.
+	Archives bool        // `Archives` walks tar and zip archives as directories of their members.
+	Skipped  func(Skip)  // `Skipped` gets the paths skipped, unless it is nil.
+	Errors   func(error) // `Errors` gets the errors of paths skipped as they can't be read, unless it is nil.
+}
+
+// A regular file found by `Walk`.
+type File struct {
+	Path   string      // `Path` is the file name relative to the directory of the manifest, in canonical form.
+	Info   fs.FileInfo // `Info` describes the file.
+	FS     fs.FS       // `FS` holds the file `Name`, unless `Reader` is given.
+	Name   string      // `Name` is the slash-separated name of the file within `FS`.
+	Reader io.Reader   // `Reader` streams the content of an archive member instead, readable only until the call returns.
+}
+
+// Walks the `roots` trees, then the listed `files`, passing each regular file to `fn`, once even if reached through
+// several roots. Listed files are relative to the working directory, and not matched against patterns.
+//
+// Paths matching `opts.Excludes` or .xxhsumignore patterns, or not matching `opts.Includes`, are skipped. So are
+// the manifest and its companions, however they are reached, listed directories, and symbolic links, named pipes,
+// sockets and devices, as reading them may block forever. With `opts.Archives`, tar and zip archives are walked
+// as directories of their members, streamed one by one in archive order.
+// Once `ctx` is done, walking stops, outputting its error.
+func Walk(ctx context.Context, roots []Root, files []string, opts WalkOptions, fn func(File)) error {
+
+	w := &walker{
+		ctx:     ctx,
+		opts:    opts,
+		fn:      fn,
+		c:       newCompanions(opts.Manifest),
+		visited: make(map[string]bool),
+	}
+
+	for _, root := range roots {
+		if ctx.Err() != nil {
+			break
+		}
+		w.walkRoot(root)
+	}
+	for _, listed := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		w.walkListed(listed)
+	}
+	return ctx.Err()
+}
+
+// `walker` holds the state of a `Walk`.
+type walker struct {
+	ctx     context.Context // `ctx` stops walking once done.
+	opts    WalkOptions     // `opts` are the settings.
+	fn      func(File)      // `fn` gets the files found.
+	c       *companions     // `c` recognises files never to be passed.
+	visited map[string]bool // `visited` holds file names of files already passed.
+}
+
+// `skip` reports the `path` skipped for `reason`.
+func (w *walker) skip(path string, reason SkipReason, fileType string) {
+	if w.opts.Skipped != nil {
+		w.opts.Skipped(Skip{Path: path, Reason: reason, Type: fileType})
+	}
+}
+
+// `fail` reports the error of a path skipped.
+func (w *walker) fail(err error) {
+	if w.opts.Errors != nil {
+		w.opts.Errors(err)
+	}
+}
+
+// `visit` passes the regular file `name` of `fsys`, found at `path`, or streamed from `r` if it is an archive member.
+func (w *walker) visit(fsys fs.FS, name string, path string, fileInfo fs.FileInfo, r io.Reader) {
+
+	relPath, err := filepath.Rel(filepath.Dir(w.opts.Manifest), path)
+	if err != nil {
+		w.fail(fmt.Errorf("error resolving relative path: %s; %w", path, err))
+		return
+	}
+	// Named in the same canonical form entries are loaded in.
+	relPath = CanonicalPath(relPath)
+
+	if w.visited[relPath] {
+		// Reached again through overlapping roots.
+		return
+	}
+	w.visited[relPath] = true
+
+	if w.c.match(path, fileInfo) {
+		w.skip(path, SkipCompanion, "")
+		return
+	}
+	w.fn(File{Path: relPath, Info: fileInfo, FS: fsys, Name: name, Reader: r})
+}
+
+// `walkRoot` walks the tree of `root`.
+func (w *walker) walkRoot(root Root) {
+
+	f := newFilter(root.FS, root.Dir, w.opts.Excludes, w.opts.Includes) // `f` selects the paths to be walked.
+
+	err := fs.WalkDir(root.FS, ".", func(name string, di fs.DirEntry, err error) error {
+		path := filepath.Join(root.Dir, filepath.FromSlash(name)) // `path` is where the file is found, for filtering and reporting.
+		if w.ctx.Err() != nil {
+			return fs.SkipAll
+		}
+		if err != nil {
+			w.fail(fmt.Errorf("error accessing path: %s; %w", path, err))
+			return nil
+		}
+
+		// Archives are walked as directories of their members.
+		archive := w.opts.Archives && di.Type().IsRegular() && IsArchive(name)
+
+		// Skip excluded paths. Skip excluded directories with all their content.
+		if name != "." && f.excluded(path, di.IsDir() || archive) {
+			w.skip(path, SkipExcluded, "")
+			if di.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if di.IsDir() {
+			if err := f.loadIgnoreFile(path); err != nil {
+				w.fail(err)
+			}
+			return nil
+		}
+
+		// Skip symbolic links, named pipes, sockets and devices, as reading them may block forever.
+		if fileType := nonRegularType(di.Type()); fileType != "" {
+			w.skip(path, SkipNonRegular, fileType)
+			return nil
+		}
+
+		if archive {
+			w.walkArchive(root.FS, name, path, f)
+			return nil
+		}
+
+		if !f.included(path) {
+			w.skip(path, SkipNotIncluded, "")
+			return nil
+		}
+
+		fileInfo, err := di.Info()
+		if err != nil {
+			w.fail(fmt.Errorf("error accessing file: %s; %w", path, err))
+			return nil
+		}
+
+		w.visit(root.FS, name, path, fileInfo, nil)
+		return nil
+	})
+
+	if err != nil {
+		w.fail(fmt.Errorf("error walking the path: %s; %w", root.Dir, err))
+	}
+}
+
+// `walkArchive` walks the members of the archive `name` of `fsys`, found at `path`, filtered by `f` unless it is nil.
+func (w *walker) walkArchive(fsys fs.FS, name string, path string, f *filter) {
+
+	err := WalkArchive(fsys, name, func(member string, fileInfo fs.FileInfo, r io.Reader) error {
+		if err := w.ctx.Err(); err != nil {
+			return err
+		}
+		memberPath := filepath.Join(path, filepath.FromSlash(member))
+
+		if f != nil && f.excludedWithin(path, memberPath) {
+			w.skip(memberPath, SkipExcluded, "")
+			return nil
+		}
+		if fileType := nonRegularType(fileInfo.Mode()); fileType != "" {
+			w.skip(memberPath, SkipNonRegular, fileType)
+			return nil
+		}
+		if f != nil && !f.included(memberPath) {
+			w.skip(memberPath, SkipNotIncluded, "")
+			return nil
+		}
+
+		w.visit(nil, "", memberPath, fileInfo, r)
+		return nil
+	})
+	if err != nil && w.ctx.Err() == nil {
+		w.fail(fmt.Errorf("error reading archive: %s; %w", path, err))
+	}
+}
+
+// `walkListed` passes the `listed` file, or walks it if it is an archive.
+func (w *walker) walkListed(listed string) {
+
+	path, err := filepath.Abs(listed)
+	if err != nil {
+		w.fail(fmt.Errorf("error resolving filepath: %s; %w", listed, err))
+		return
+	}
+
+	fileInfo, err := os.Lstat(path)
+	if err != nil {
+		w.fail(fmt.Errorf("error accessing file: %s; %w", path, err))
+		return
+	}
+
+	// Skip listed directories, as only the listed files are walked. Skip other non-regular files, as when walking.
+	if fileInfo.IsDir() {
+		w.skip(path, SkipDirectory, "")
+		return
+	}
+	if fileType := nonRegularType(fileInfo.Mode()); fileType != "" {
+		w.skip(path, SkipNonRegular, fileType)
+		return
+	}
+
+	if w.opts.Archives && IsArchive(path) {
+		w.walkArchive(DirFS(filepath.Dir(path)), filepath.Base(path), path, nil)
+		return
+	}
+	w.visit(DirFS(filepath.Dir(path)), filepath.Base(path), path, fileInfo, nil)
+}
+
+// Outputs the type of non-regular file of `mode`, or empty string for regular files and directories.
+func nonRegularType(mode fs.FileMode) string {
+	switch true {
+	case mode.IsRegular(), mode.IsDir():
+		return ""
+	case mode&fs.ModeSymlink != 0:
+		return "symbolic link"
+	case mode&fs.ModeNamedPipe != 0:
+		return "named pipe"
+	case mode&fs.ModeSocket != 0:
+		return "socket"
+	case mode&fs.ModeCharDevice != 0:
+		return "character device"
+	case mode&fs.ModeDevice != 0:
+		return "block device"
+	default:
+		return "irregular file"
+	}
+}
@@ -0,0 +1,87 @@
+package manifest
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"listed", "sub/a"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fsys := fstest.MapFS{
+		"a":     {Data: []byte("a")},
+		"b/c":   {Data: []byte("c")},
+		"link":  {Data: []byte("a"), Mode: fs.ModeSymlink},
+		"pipe":  {Mode: fs.ModeNamedPipe},
+		"b/d.x": {Data: []byte("d")},
+	}
+	// The second root overlaps the first one.
+	roots := []Root{{FS: fsys, Dir: filepath.Join(dir, "sub")}, {FS: fstest.MapFS{"c": {Data: []byte("c")}}, Dir: filepath.Join(dir, "sub", "b")}}
+
+	got, skipped := []string{}, []Skip{}
+	opts := WalkOptions{Manifest: filepath.Join(dir, "test.xxhsum"), Excludes: []string{"*.x"}, Skipped: func(skip Skip) { skipped = append(skipped, skip) }}
+	files := []string{filepath.Join(dir, "listed"), filepath.Join(dir, "sub")}
+	if err := Walk(context.Background(), roots, files, opts, func(file File) { got = append(got, file.Path) }); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"sub/a", "sub/b/c", "listed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Walk() = %v, want %v", got, want)
+	}
+	wantSkipped := []Skip{
+		{filepath.Join(dir, "sub", "b", "d.x"), SkipExcluded, ""},
+		{filepath.Join(dir, "sub", "link"), SkipNonRegular, "symbolic link"},
+		{filepath.Join(dir, "sub", "pipe"), SkipNonRegular, "named pipe"},
+		{filepath.Join(dir, "sub"), SkipDirectory, ""},
+	}
+	if !reflect.DeepEqual(skipped, wantSkipped) {
+		t.Errorf("Walk() skipped %v, want %v", skipped, wantSkipped)
+	}
+}
+
+func TestWalk_cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got := []string{}
+	err := Walk(ctx, []Root{{FS: fstest.MapFS{"a": {Data: []byte("a")}}, Dir: t.TempDir()}}, nil, WalkOptions{}, func(file File) { got = append(got, file.Path) })
+	if err != context.Canceled || len(got) != 0 {
+		t.Errorf("Walk() = %v, %v, want %v and nothing walked", got, err, context.Canceled)
+	}
+}
+
+func Test_nonRegularType(t *testing.T) {
+	tests := []struct {
+		name string
+		mode fs.FileMode
+		want string
+	}{
+		{"REGULAR", 0644, ""},
+		{"DIRECTORY", fs.ModeDir | 0755, ""},
+		{"SYMLINK", fs.ModeSymlink | 0777, "symbolic link"},
+		{"NAMED_PIPE", fs.ModeNamedPipe | 0644, "named pipe"},
+		{"SOCKET", fs.ModeSocket | 0755, "socket"},
+		{"CHAR_DEVICE", fs.ModeDevice | fs.ModeCharDevice | 0666, "character device"},
+		{"BLOCK_DEVICE", fs.ModeDevice | 0660, "block device"},
+		{"IRREGULAR", fs.ModeIrregular, "irregular file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nonRegularType(tt.mode); got != tt.want {
+				t.Errorf("nonRegularType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package manifest
+
+import (
+	"fmt"
+	"io"
+)
+
+// Writes entries as manifest lines of one style.
+type Writer struct {
+	w     io.Writer // `w` receives one complete line per write.
+	style Style     // `style` is the style of the lines written.
+	zero  bool      // `zero` terminates lines by NUL, leaving file names unescaped.
+}
+
+// Outputs a writer of `style` lines to `w`, terminated by NUL if `zero`.
+// Each line is passed to `w` in a single write, so that writers buffering complete lines, like `Appender`, never split one.
+func NewWriter(w io.Writer, style Style, zero bool) *Writer {
+	return &Writer{w: w, style: style, zero: zero}
+}
+
+// Writes the line of the entry `e`, see `Entry.Line`.
+func (w *Writer) Write(e Entry) error {
+	_, err := io.WriteString(w.w, e.Line(w.style, w.zero))
+	return err
+}
+
+// Writes the heading comment of a new manifest of `algorithm` hashes. Only GNU-style lines terminated by newline get one,
+// so that BSD-style and NUL-terminated manifests hold nothing but entries.
+func (w *Writer) WriteHeader(algorithm Algorithm) error {
+	if w.style != GNU || w.zero {
+		return nil
+	}
+	_, err := fmt.Fprintf(w.w, "# %s hashes https://xxhash.com/\n# To verify use xxhsum --check --quiet FILEPATH\n", algorithm.Tag)
+	return err
+}
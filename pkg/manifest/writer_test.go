@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		style Style
+		zero  bool
+		want  string
+	}{
+		{"GNU", GNU, false, "# XXH64 hashes https://xxhash.com/\n# To verify use xxhsum --check --quiet FILEPATH\n0d3148243051664f *a\n\\XXH3_2d06800538d394c2 *b\\nc\n"},
+		{"BSD", BSD, false, "XXH64 (a) = 0d3148243051664f\n\\XXH3 (b\\nc) = 2d06800538d394c2\n"},
+		{"GNU_ZERO", GNU, true, "0d3148243051664f *a\x00XXH3_2d06800538d394c2 *b\nc\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got strings.Builder
+			w := NewWriter(&got, tt.style, tt.zero)
+			if err := w.WriteHeader(XXH64); err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range []Entry{{"a", "0d3148243051664f", XXH64}, {"b\nc", "2d06800538d394c2", XXH3}} {
+				if err := w.Write(entry); err != nil {
+					t.Fatalf("Writer.Write() error = %v", err)
+				}
+			}
+			if got.String() != tt.want {
+				t.Errorf("Writer wrote %q, want %q", got.String(), tt.want)
+			}
+
+			// What is written reads back the same.
+			m := New()
+			if err := Scan(strings.NewReader(got.String()), tt.zero, func(e Entry) error { m.Add(e); return nil }); err != nil || m.Len() != 2 {
+				t.Errorf("Scan() of written lines = %v, %v, want 2 entries", m.Paths(), err)
+			}
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"NEW", "", "0d3148243051664f *a\n"},
+		{"TERMINATED", "02cc5d05 *b\n", "02cc5d05 *b\n0d3148243051664f *a\n"},
+		{"UNTERMINATED", "02cc5d05 *b\n02cc5d05 *c/d", "02cc5d05 *b\n0d3148243051664f *a\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputFile := filepath.Join(t.TempDir(), "test.xxhsum")
+			if tt.content != "" {
+				if err := os.WriteFile(outputFile, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := Append(outputFile, GNU, false, Entry{"a", "0d3148243051664f", XXH64}); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			if got, err := os.ReadFile(outputFile); err != nil {
+				t.Fatal(err)
+			} else if string(got) != tt.want {
+				t.Errorf("Append() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
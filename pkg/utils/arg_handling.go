@@ -12,21 +12,49 @@ import (
 
 // Text of help.
 const Usage string = `
-Usage: %s [--xxhsum-filepath FILEPATH] [--bsd-style] [--verbose] [--debug] [--help] PATH
+Usage: %s [--xxhsum-filepath FILEPATH] [--bsd-style] [--algorithm ALGORITHM] [--check] [--prune [--dry-run]] [--update [--rehash] [--dry-run]] [--repair [--dry-run]] [--strict] [--exclude PATTERN]... [--include PATTERN]... [--files-from FILE [--null]] [--jobs N] [--keep-order] [--skip-report] [--zero] [--wait] [--index] [--archives] [--prescan] [--verbose] [--debug] [--help] PATH...
 
-Recursively adds missing xxhsum (XXH64) hashes from PATH to --xxhsum-filepath.
+Recursively adds missing xxhsum (XXH64 by default) hashes from each PATH to --xxhsum-filepath.
+With --check, verifies hashes listed in --xxhsum-filepath instead.
+With --prune, removes lines of files that no longer exist from --xxhsum-filepath instead.
+With --repair, removes malformed lines from --xxhsum-filepath instead.
+With --update, also replaces lines of files that changed since they were hashed.
 
 Arguments:
-  PATH                     PATH to analyze
+  PATH                     PATH to analyze. Repeatable, if -x is given. Optional with --files-from, if -x is given
 
 Parameters:
   -x, --xxhsum-filepath    FILEPATH of file to append to. Defaults to PATH\..\DIRNAME.xxhsum
-  -b, --bsd-style          BSD-style checksum lines. Defaults to GNU-style
+  -b, --bsd-style          BSD-style checksum lines. Defaults to GNU-style. Must match existing lines
+  -a, --algorithm          hash ALGORITHM of new lines: xxh32, xxh64, xxh3 or xxh128. Defaults to xxh64
+  -c, --check              verify hashes listed in --xxhsum-filepath. PATH is optional if -x is given
+  -p, --prune              remove lines of files that no longer exist. PATH is optional if -x is given
+  -u, --update             re-hash listed files whose size, mtime or inode changed, and replace lines of changed ones
+  -r, --rehash             with --update, re-hash every listed file regardless of size, mtime and inode
+  -I, --index              keep the index of sizes, mtimes and inodes up to date when appending too, and report listed files modified since
+  -A, --archives           hash members of tar and zip archives as files of a directory named after the archive, instead of the archive
+  -P, --prescan            count files and bytes to hash before hashing, to show percent done and ETA
+  -R, --repair             remove malformed lines, e.g. left by an interrupted append. PATH is optional if -x is given
+  -S, --strict             fail on malformed lines and on file names listed more than once
+  -n, --dry-run            report lines --prune, --update or --repair would change, without writing anything
+  -e, --exclude            skip paths matching gitignore-style PATTERN, relative to PATH. Repeatable
+  -i, --include            hash only files matching gitignore-style PATTERN, or within matching directories. Repeatable
+  -f, --files-from         hash only files listed in FILE, one per line, or in stdin if FILE is -
+  -0, --null               with --files-from, files are separated by NUL, as find -print0 does
+  -j, --jobs               number of files hashed in parallel. Defaults to the number of CPUs
+  -k, --keep-order         append lines in walk order. Defaults to completion order
+  -s, --skip-report        report the number of skipped symbolic links, named pipes, sockets and devices
+  -z, --zero               NUL-terminated lines of --xxhsum-filepath, with file names not escaped, as sha256sum -z
+  -w, --wait               wait for other runs to release --xxhsum-filepath. Defaults to failing at once
   -v, --verbose            increase the verbosity
   -d, --debug              show debug information
   -h, --help               show this help message and exit
 
-To verify use xxhsum --check --quiet FILEPATH
+Patterns are also read from .xxhsumignore files in PATH and its subdirectories, relative to their directory.
+The xxhsum file and its companion files (.meta, .lock and temporary .tmp files) are never hashed.
+Only regular files are hashed. Symbolic links, named pipes, sockets and devices are skipped.
+
+To verify use --check, or xxhsum --check --quiet FILEPATH
 
 version: %s
 `
@@ -123,3 +151,17 @@ func expandTilde(inputPath string) (string, error) {
 		}
 	}
 }
+
+// Collects values of a parameter given multiple times.
+type StringList []string
+
+// Outputs the values, comma-separated.
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Adds a value.
+func (l *StringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
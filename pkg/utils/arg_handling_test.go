@@ -1,10 +1,28 @@
 package utils
 
 import (
+	"os"
+	"os/user"
+	"path/filepath"
 	"testing"
 )
 
+// Outputs the home directory of the current user, as `expandTilde` resolves `~`.
+func homeDir(t *testing.T) string {
+	usr, err := user.Current()
+	if err != nil {
+		t.Skipf("no current user; %v", err)
+	}
+	home, err := filepath.Abs(usr.HomeDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return home
+}
+
 func Test_expandTilde(t *testing.T) {
+	home := homeDir(t)
+
 	type args struct {
 		inputPath string
 	}
@@ -20,11 +38,11 @@ func Test_expandTilde(t *testing.T) {
 		{"NO_CHANGE4", args{"/kolo/Dmenats"}, "/kolo/Dmenats", false},
 		{"NO_CHANGE5", args{"kolo/Domenats/"}, "kolo/Domenats", false},
 		{"NO_CHANGE6", args{"/kolo/Domenats/"}, "/kolo/Domenats", false},
-		{"EXPAND_JUST TILDE", args{"~"}, "/home/lukasz", false},
-		{"EXPAND_TILDE_PREFIX1", args{"~/Documents"}, "/home/lukasz/Documents", false},
-		{"EXPAND_TILDE_PREFIX2", args{"~/Documents/"}, "/home/lukasz/Documents", false},
-		{"EXPAND_TILDE_PREFIX3", args{"~/Documents/Bulba"}, "/home/lukasz/Documents/Bulba", false},
-		{"EXPAND_TILDE_PREFIX4", args{"~/kolo/~/Documenats"}, "/home/lukasz/kolo/~/Documenats", false},
+		{"EXPAND_JUST TILDE", args{"~"}, home, false},
+		{"EXPAND_TILDE_PREFIX1", args{"~/Documents"}, filepath.Join(home, "Documents"), false},
+		{"EXPAND_TILDE_PREFIX2", args{"~/Documents/"}, filepath.Join(home, "Documents"), false},
+		{"EXPAND_TILDE_PREFIX3", args{"~/Documents/Bulba"}, filepath.Join(home, "Documents", "Bulba"), false},
+		{"EXPAND_TILDE_PREFIX4", args{"~/kolo/~/Documenats"}, filepath.Join(home, "kolo", "~", "Documenats"), false},
 		{"GIBBERISH1", args{"~kolo/Documenats"}, "", true},
 		{"GIBBERISH2", args{"~kolo/Documenats/"}, "", true},
 		{"GIBBERISH3", args{"~.kolo/Documenats/"}, "", true},
@@ -44,6 +62,12 @@ func Test_expandTilde(t *testing.T) {
 }
 
 func TestArgParse(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".profile")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
 	type args struct {
 		arg     string
 		verbose bool
@@ -54,13 +78,12 @@ func TestArgParse(t *testing.T) {
 		want    string
 		wantErr bool
 	}{
-		{"DIR", args{"/home/lukasz", true}, "/home/lukasz", false},
-		{"DIR", args{"/home/lukasz/", true}, "/home/lukasz", false},
+		{"DIR", args{dir, true}, dir, false},
+		{"DIR", args{dir + "/", true}, dir, false},
 		{"ROOT PATH", args{"/", true}, "/", false},
 		{"NON-EXISTING", args{"./Bulba", true}, "", true},
-		{"NODIR", args{"/home/lukasz/.profile", true}, "", true},
-		{"WRONG PATH", args{"/home/luksza/.profile", true}, "", true},
-		{"ROOT HOMEDIR", args{"/root/snap", true}, "", true},
+		{"NODIR", args{file, true}, "", true},
+		{"WRONG PATH", args{filepath.Join(dir, "luksza", ".profile"), true}, "", true},
 		{"GIBBERISH", args{"|.~", true}, "", true},
 	}
 	for _, tt := range tests {
@@ -78,6 +101,13 @@ func TestArgParse(t *testing.T) {
 }
 
 func TestParamParse(t *testing.T) {
+	home := homeDir(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, ".profile")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
 	type args struct {
 		param   string
 		verbose bool
@@ -89,13 +119,12 @@ func TestParamParse(t *testing.T) {
 		want1   bool
 		wantErr bool
 	}{
-		{"FILE", args{"/home/lukasz/.profile", true}, "/home/lukasz/.profile", true, false},
-		{"NON EXISTING DIR", args{"/home/luksiz/.profile", true}, "/home/luksiz/.profile", false, false},
-		{"FILE with TILDE", args{"~/.profile", true}, "/home/lukasz/.profile", true, false},
-		{"NON EXISTING FILE with TILDE", args{"~/.profilwee", true}, "/home/lukasz/.profilwee", false, false},
-		{"DIR", args{"/home/lukasz/Documents", true}, "", true, true},
-		{"DIR", args{"/home/lukasz/Documents/", true}, "", true, true},
-		{"ROOT HOMEDIR FILE", args{"/root/.profile", true}, "", false, true},
+		{"FILE", args{file, true}, file, true, false},
+		{"NON EXISTING DIR", args{filepath.Join(dir, "luksiz", ".profile"), true}, filepath.Join(dir, "luksiz", ".profile"), false, false},
+		{"NON EXISTING FILE with TILDE", args{"~/.profilwee", true}, filepath.Join(home, ".profilwee"), false, false},
+		{"DIR", args{dir, true}, "", true, true},
+		{"DIR", args{dir + "/", true}, "", true, true},
+		{"DIR with TILDE", args{"~", true}, "", true, true},
 		{"FILE WITH WRONG TILDE", args{"~.profile", true}, "", false, true},
 	}
 	for _, tt := range tests {
@@ -36,13 +36,20 @@ func TestLoadXXHSumFile(t *testing.T) {
 	}{
 		{"NO_FILE1", args{"/Bulba", false}, nil, true},
 		{"NO_FILE2", args{"/Bulba", true}, nil, true},
-		{"WRONG_FILE1", args{"/home/lukasz/.profile", true}, make(map[string]string), false},
-		{"WRONG_FILE2", args{"/home/lukasz/.gitcommitmessage.txt", false}, make(map[string]string), false},
-		{"FILEA", args{"/home/lukasz/Code/golang/append-xxhsum/tst/test1.xxhsum", false}, map[string]string{
-			"./golang/goroot/go.mod": "1f809539dbc4e242", "./golang/goroot/sample-app/sample-app": "76b9b81ec1c51248"},
+		{"WRONG_FILE1", args{"../../go.mod", true}, make(map[string]string), false},
+		{"WRONG_FILE2", args{"../../LICENSE", false}, make(map[string]string), false},
+		{"FILEA", args{"../../tst/test1.xxhsum", false}, map[string]string{
+			"golang/goroot/go.mod": "1f809539dbc4e242", "golang/goroot/sample-app/sample-app": "76b9b81ec1c51248"},
 			false},
-		{"FILEB", args{"/home/lukasz/Code/golang/append-xxhsum/tst/test2.xxhsum", true}, map[string]string{
-			"./golang/goroot/go.mod": "1f809539dbc4e242", "./golang/goroot/sample-app/sample-app": "76b9b81ec1c51248"},
+		{"FILEB", args{"../../tst/test2.xxhsum", true}, map[string]string{
+			"golang/goroot/go.mod": "1f809539dbc4e242", "golang/goroot/sample-app/sample-app": "76b9b81ec1c51248"},
+			false},
+		// The style is detected line by line, whatever `bsdStyle` says.
+		{"FILEB_GNU", args{"../../tst/test2.xxhsum", false}, map[string]string{
+			"golang/goroot/go.mod": "1f809539dbc4e242", "golang/goroot/sample-app/sample-app": "76b9b81ec1c51248"},
+			false},
+		{"MIXED", args{"../../tst/test5.xxhsum", false}, map[string]string{
+			"a.txt": "02cc5d05", "b.txt": "ef46db3751d8e999", "c.txt": "XXH3_2d06800538d394c2", "d (1).txt": "99aa06d3014798d86001c324468d497f"},
 			false},
 	}
 	for _, tt := range tests {
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lukasz-lobocki/append-xxhsum/pkg/manifest"
+)
+
+// Name of the list file meaning standard input.
+const Stdin string = "-"
+
+// Loads the list of files to be hashed, one per line, from `inputFile` or from standard input if it is `Stdin`.
+// With `null` files are separated by NUL instead of newline, so that names may contain line breaks. Empty lines are ignored.
+func LoadFileList(inputFile string, null bool) ([]string, error) {
+
+	var (
+		reader  io.Reader      = os.Stdin
+		scanner *bufio.Scanner = nil
+		files   []string       = []string{}
+	)
+
+	if inputFile != Stdin {
+		file, err := os.Open(inputFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening file: %s; %w", inputFile, err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	scanner = bufio.NewScanner(reader)
+	scanner.Split(manifest.ScanLines(null))
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			files = append(files, line)
+		}
+	}
+
+	// Check for any scanning errors
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error scanning: %s; %w", inputFile, err)
+	}
+
+	return files, nil
+}
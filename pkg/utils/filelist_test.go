@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadFileList(t *testing.T) {
+	type args struct {
+		content string
+		null    bool
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		wantErr bool
+	}{
+		{"EMPTY", args{"", false}, []string{}, false},
+		{"LINES", args{"a\n./b c\n\nsub/d\n", false}, []string{"a", "./b c", "sub/d"}, false},
+		{"NO_TRAILING_NEWLINE", args{"a\nb", false}, []string{"a", "b"}, false},
+		{"NUL", args{"a\nb\x00c\x00\x00d", true}, []string{"a\nb", "c", "d"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputFile := filepath.Join(t.TempDir(), "list")
+			if err := os.WriteFile(inputFile, []byte(tt.args.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadFileList(inputFile, tt.args.null)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadFileList() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadFileList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadFileList("/Bulba", false); err == nil {
+		t.Errorf("LoadFileList() error = %v, wantErr %v", err, true)
+	}
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Reported by `lockFile` if the lock is held by another run and waiting was not requested.
+var errWouldBlock = errors.New("lock held by another run")
+
+// Interval at which `AcquireLockContext` retries a lock held by another run.
+const lockRetryInterval time.Duration = 100 * time.Millisecond
+
+// Advisory lock on xxhsum_file, held through a lock file next to it for the whole run.
+//
+// The lock file is never removed, as removing it would let two runs lock two different files of the same name.
+type Lock struct {
+	file   *os.File // `file` is the open lock file.
+	shared bool     // `shared` is set for locks several runs may hold at once.
+}
+
+// Error of a lock held by another run.
+type LockedError struct {
+	Path string // `Path` is the lock file.
+	PID  int    // `PID` is the process holding the lock, or 0 if unknown.
+}
+
+// Outputs the message of the error, naming the process holding the lock if known.
+func (e *LockedError) Error() string {
+	if e.PID > 0 {
+		return fmt.Sprintf("%s is locked by process %d", e.Path, e.PID)
+	}
+	return fmt.Sprintf("%s is locked by another process", e.Path)
+}
+
+// Outputs the filepath of the lock file kept alongside xxhsum_file.
+func LockFilepath(xxhsumFilepath string) string {
+	return xxhsumFilepath + ".lock"
+}
+
+// Locks `lockFilepath`, creating it if it doesn't exist. A `shared` lock may be held by several runs at once,
+// an exclusive one by a single run, holding no shared ones either. The holder of an exclusive lock writes its PID to the lock file.
+//
+// With `wait` it blocks until the lock is available. Otherwise it fails at once with `LockedError`.
+func AcquireLock(lockFilepath string, shared bool, wait bool) (*Lock, error) {
+
+	var (
+		file *os.File = nil
+		err  error    = nil
+	)
+
+	if file, err = os.OpenFile(lockFilepath, os.O_RDWR|os.O_CREATE, 0644); err != nil && shared {
+		// Shared locks need no writing, so an existing lock file in a read-only location will do.
+		file, err = os.Open(lockFilepath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %s; %w", lockFilepath, err)
+	}
+
+	if err = lockFile(file, shared, wait); err != nil {
+		file.Close()
+		if errors.Is(err, errWouldBlock) {
+			return nil, &LockedError{Path: lockFilepath, PID: readPID(lockFilepath)}
+		}
+		return nil, fmt.Errorf("error locking file: %s; %w", lockFilepath, err)
+	}
+
+	if !shared {
+		if err = writePID(file); err != nil {
+			unlockFile(file)
+			file.Close()
+			return nil, fmt.Errorf("error writing file: %s; %w", lockFilepath, err)
+		}
+	}
+
+	return &Lock{file: file, shared: shared}, nil
+}
+
+// Locks `lockFilepath` as `AcquireLock` does. With `wait` it retries until the lock is available, or until `ctx` is done,
+// outputting its error then.
+func AcquireLockContext(ctx context.Context, lockFilepath string, shared bool, wait bool) (*Lock, error) {
+
+	var (
+		lockedErr *LockedError = nil
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		lock, err := AcquireLock(lockFilepath, shared, false)
+		if !wait || !errors.As(err, &lockedErr) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+// Releases the lock, clearing the PID of an exclusive one.
+func (l *Lock) Release() error {
+
+	if !l.shared {
+		if err := l.file.Truncate(0); err != nil {
+			unlockFile(l.file)
+			l.file.Close()
+			return fmt.Errorf("error writing file: %s; %w", l.file.Name(), err)
+		}
+	}
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return fmt.Errorf("error unlocking file: %s; %w", l.file.Name(), err)
+	}
+	return l.file.Close()
+}
+
+// Replaces the content of the lock `file` with the PID of this process.
+func writePID(file *os.File) error {
+	if err := file.Truncate(0); err != nil {
+		return err
+	}
+	_, err := file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return err
+}
+
+// Outputs the PID written to the lock file, or 0 if there is none.
+func readPID(lockFilepath string) int {
+	if content, err := os.ReadFile(lockFilepath); err == nil {
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(content))); err == nil && pid > 0 {
+			return pid
+		}
+	}
+	return 0
+}
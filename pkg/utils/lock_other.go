@@ -0,0 +1,17 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package utils
+
+import (
+	"os"
+)
+
+// Advisory locks are not available on this platform, so runs are not serialised.
+func lockFile(file *os.File, shared bool, wait bool) error {
+	return nil
+}
+
+// Unlocks the `file` locked by `lockFile`.
+func unlockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,133 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestLockFilepath(t *testing.T) {
+	if got := LockFilepath("/home/lukasz/test.xxhsum"); got != "/home/lukasz/test.xxhsum.lock" {
+		t.Errorf("LockFilepath() = %v, want %v", got, "/home/lukasz/test.xxhsum.lock")
+	}
+}
+
+func TestAcquireLock(t *testing.T) {
+	type args struct {
+		first  bool
+		second bool
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantLocked bool
+		wantPID    int
+	}{
+		{"EXCLUSIVE_EXCLUSIVE", args{false, false}, true, os.Getpid()},
+		{"EXCLUSIVE_SHARED", args{false, true}, true, os.Getpid()},
+		{"SHARED_EXCLUSIVE", args{true, false}, true, 0},
+		{"SHARED_SHARED", args{true, true}, false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockFilepath := LockFilepath(filepath.Join(t.TempDir(), "test.xxhsum"))
+
+			first, err := AcquireLock(lockFilepath, tt.args.first, false)
+			if err != nil {
+				t.Fatalf("AcquireLock() error = %v", err)
+			}
+			defer first.Release()
+
+			second, err := AcquireLock(lockFilepath, tt.args.second, false)
+			var lockedErr *LockedError
+			if got := errors.As(err, &lockedErr); got != tt.wantLocked {
+				t.Fatalf("AcquireLock() error = %v, wantLocked %v", err, tt.wantLocked)
+			}
+			if tt.wantLocked && lockedErr.PID != tt.wantPID {
+				t.Errorf("AcquireLock() PID = %v, want %v", lockedErr.PID, tt.wantPID)
+			}
+			if second != nil {
+				second.Release()
+			}
+		})
+	}
+}
+
+func TestAcquireLock_wait(t *testing.T) {
+	lockFilepath := LockFilepath(filepath.Join(t.TempDir(), "test.xxhsum"))
+
+	first, err := AcquireLock(lockFilepath, false, false)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+
+	acquired := make(chan *Lock)
+	go func() {
+		second, err := AcquireLock(lockFilepath, false, true)
+		if err != nil {
+			t.Errorf("AcquireLock() error = %v", err)
+		}
+		acquired <- second
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("AcquireLock() did not wait for the lock")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("Lock.Release() error = %v", err)
+	}
+
+	select {
+	case second := <-acquired:
+		if second == nil {
+			return
+		}
+		if content, err := os.ReadFile(lockFilepath); err != nil || string(content) != strconv.Itoa(os.Getpid())+"\n" {
+			t.Errorf("AcquireLock() wrote %q, %v, want PID", content, err)
+		}
+		if err := second.Release(); err != nil {
+			t.Errorf("Lock.Release() error = %v", err)
+		}
+		if content, err := os.ReadFile(lockFilepath); err != nil || len(content) != 0 {
+			t.Errorf("Lock.Release() left %q, %v, want empty", content, err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("AcquireLock() kept waiting after release")
+	}
+}
+
+func TestAcquireLockContext(t *testing.T) {
+	lockFilepath := LockFilepath(filepath.Join(t.TempDir(), "test.xxhsum"))
+
+	first, err := AcquireLock(lockFilepath, false, false)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+
+	// Waiting for the lock held stops once the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 3*lockRetryInterval)
+	defer cancel()
+	if lock, err := AcquireLockContext(ctx, lockFilepath, false, true); lock != nil || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("AcquireLockContext() = %v, %v, want no lock and %v", lock, err, context.DeadlineExceeded)
+	}
+
+	// Waiting for the lock held ends once it is released.
+	go func() {
+		time.Sleep(2 * lockRetryInterval)
+		first.Release()
+	}()
+	lock, err := AcquireLockContext(context.Background(), lockFilepath, false, true)
+	if err != nil {
+		t.Fatalf("AcquireLockContext() error = %v", err)
+	}
+	lock.Release()
+}
@@ -0,0 +1,38 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Locks the `file` with flock. Outputs `errWouldBlock` if it is held by another run and `wait` is not set.
+func lockFile(file *os.File, shared bool, wait bool) error {
+
+	how := syscall.LOCK_EX
+	if shared {
+		how = syscall.LOCK_SH
+	}
+	if !wait {
+		how |= syscall.LOCK_NB
+	}
+
+	for {
+		err := syscall.Flock(int(file.Fd()), how)
+		switch true {
+		case errors.Is(err, syscall.EINTR):
+			continue
+		case errors.Is(err, syscall.EWOULDBLOCK):
+			return errWouldBlock
+		default:
+			return err
+		}
+	}
+}
+
+// Unlocks the `file` locked by `lockFile`.
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// Locked byte range of the lock file lies far beyond its content, as Windows keeps other processes from reading locked bytes,
+// and the PID must stay readable.
+const lockOffsetHigh uint32 = 0x7fffffff
+
+// Locks the `file` with LockFileEx. Outputs `errWouldBlock` if it is held by another run and `wait` is not set.
+func lockFile(file *os.File, shared bool, wait bool) error {
+
+	var flags uint32 = 0
+	if !shared {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	if !wait {
+		flags |= windows.LOCKFILE_FAIL_IMMEDIATELY
+	}
+
+	err := windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, &windows.Overlapped{OffsetHigh: lockOffsetHigh})
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errWouldBlock
+	}
+	return err
+}
+
+// Unlocks the `file` locked by `lockFile`.
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, &windows.Overlapped{OffsetHigh: lockOffsetHigh})
+}